@@ -1,9 +1,13 @@
 package ddddGocr
 
 import (
+	"context"
 	"encoding/base64"
-	"fmt"
+	"image"
+	"io"
+	"net/url"
 	"os"
+	"strings"
 
 	"github.com/Dainsleif233/ddddGocr/ddddgocr"
 )
@@ -23,57 +27,226 @@ const (
 	Standard   SlideMatchType = "standard"
 	Enhanced   SlideMatchType = "enhanced"
 	Comparison SlideMatchType = "comparison"
+	Gap        SlideMatchType = "gap"     // 只根据背景定位缺口，忽略目标图片
+	Contour    SlideMatchType = "contour" // 比较滑块透明通道轮廓与背景中封闭轮廓的形状
 )
 
-// 目标图片路径、背景图片路径/Base64编码、匹配方式、匹配引擎，
-// 比较模式的背景图为完整图片
-func SlideMatch(targetStr, backgroundStr string, matchType SlideMatchType, matchEngine MatchEngine) (*ddddgocr.SlideBBox, error) {
-	var targetData, backgroundData []byte
-	_, err := os.Stat(targetStr)
-	if err == nil {
-		targetData, err = os.ReadFile(targetStr)
+// 目标图片路径、背景图片路径/Base64编码、匹配方式、匹配引擎、匹配参数，
+// Base64支持data URI、标准与URL安全字母表、有无填充，比较模式的背景图为完整图片，缺口模式不使用目标图片
+func SlideMatch(targetStr, backgroundStr string, matchType SlideMatchType, matchEngine MatchEngine, opts ...ddddgocr.Option) (*ddddgocr.SlideResult, error) {
+	return SlideMatchContext(context.Background(), targetStr, backgroundStr, matchType, matchEngine, opts...)
+}
+
+// 可取消的SlideMatch，ctx取消或超时后返回ctx.Err()
+func SlideMatchContext(ctx context.Context, targetStr, backgroundStr string, matchType SlideMatchType, matchEngine MatchEngine, opts ...ddddgocr.Option) (*ddddgocr.SlideResult, error) {
+	targetData, err := readImageArg(targetStr, ddddgocr.RoleTarget)
+	if err != nil {
+		return nil, err
+	}
+	backgroundData, err := readImageArg(backgroundStr, ddddgocr.RoleBackground)
+	if err != nil {
+		return nil, err
+	}
+
+	return SlideMatchWithByteContext(ctx, targetData, backgroundData, matchType, matchEngine, opts...)
+}
+
+// 目标图片、背景图片、匹配方式、匹配引擎、匹配参数，
+// 比较模式的背景图为完整图片，缺口模式不使用目标图片
+func SlideMatchWithByte(targetData, backgroundData []byte, matchType SlideMatchType, matchEngine MatchEngine, opts ...ddddgocr.Option) (*ddddgocr.SlideResult, error) {
+	return SlideMatchWithByteContext(context.Background(), targetData, backgroundData, matchType, matchEngine, opts...)
+}
+
+// 可取消的SlideMatchWithByte，ctx取消或超时后返回ctx.Err()
+func SlideMatchWithByteContext(ctx context.Context, targetData, backgroundData []byte, matchType SlideMatchType, matchEngine MatchEngine, opts ...ddddgocr.Option) (*ddddgocr.SlideResult, error) {
+	engine, err := lookupEngine(matchEngine)
+	if err != nil {
+		return nil, err
+	}
+
+	return engine.Match(ctx, &MatchRequest{
+		Type:       matchType,
+		Target:     targetData,
+		Background: backgroundData,
+		Options:    opts,
+	})
+}
+
+// 已解码的目标图片、背景图片、匹配方式、匹配引擎、匹配参数，
+// 比较模式的背景图为完整图片，缺口模式不使用目标图片
+func SlideMatchWithImage(targetImg, backgroundImg image.Image, matchType SlideMatchType, matchEngine MatchEngine, opts ...ddddgocr.Option) (*ddddgocr.SlideResult, error) {
+	return SlideMatchWithImageContext(context.Background(), targetImg, backgroundImg, matchType, matchEngine, opts...)
+}
+
+// 可取消的SlideMatchWithImage，ctx取消或超时后返回ctx.Err()
+func SlideMatchWithImageContext(ctx context.Context, targetImg, backgroundImg image.Image, matchType SlideMatchType, matchEngine MatchEngine, opts ...ddddgocr.Option) (*ddddgocr.SlideResult, error) {
+	if targetImg == nil && matchType != Gap {
+		return nil, &ddddgocr.DecodeError{Image: ddddgocr.RoleTarget, Err: ddddgocr.ErrEmptyImage}
+	}
+	if backgroundImg == nil {
+		return nil, &ddddgocr.DecodeError{Image: ddddgocr.RoleBackground, Err: ddddgocr.ErrEmptyImage}
+	}
+
+	engine, err := lookupEngine(matchEngine)
+	if err != nil {
+		return nil, err
+	}
+
+	return engine.Match(ctx, &MatchRequest{
+		Type:            matchType,
+		TargetImage:     targetImg,
+		BackgroundImage: backgroundImg,
+		Options:         opts,
+	})
+}
+
+// 目标图片、背景图片的数据流、匹配方式、匹配引擎、匹配参数，
+// 比较模式的背景图为完整图片，缺口模式不使用目标图片
+func SlideMatchWithReader(target, background io.Reader, matchType SlideMatchType, matchEngine MatchEngine, opts ...ddddgocr.Option) (*ddddgocr.SlideResult, error) {
+	return SlideMatchWithReaderContext(context.Background(), target, background, matchType, matchEngine, opts...)
+}
+
+// 可取消的SlideMatchWithReader，ctx取消或超时后返回ctx.Err()
+func SlideMatchWithReaderContext(ctx context.Context, target, background io.Reader, matchType SlideMatchType, matchEngine MatchEngine, opts ...ddddgocr.Option) (*ddddgocr.SlideResult, error) {
+	if target == nil && matchType != Gap {
+		return nil, &ddddgocr.DecodeError{Image: ddddgocr.RoleTarget, Err: ddddgocr.ErrEmptyImage}
+	}
+	if background == nil {
+		return nil, &ddddgocr.DecodeError{Image: ddddgocr.RoleBackground, Err: ddddgocr.ErrEmptyImage}
+	}
+
+	var targetData []byte
+	if target != nil {
+		data, err := io.ReadAll(target)
 		if err != nil {
-			return nil, fmt.Errorf("读取目标图像文件失败: %v", err)
+			return nil, &ddddgocr.DecodeError{Image: ddddgocr.RoleTarget, Err: err}
 		}
-	} else {
-		targetData, err = base64.StdEncoding.DecodeString(targetStr)
+		targetData = data
+	}
+
+	backgroundData, err := io.ReadAll(background)
+	if err != nil {
+		return nil, &ddddgocr.DecodeError{Image: ddddgocr.RoleBackground, Err: err}
+	}
+
+	return SlideMatchWithByteContext(ctx, targetData, backgroundData, matchType, matchEngine, opts...)
+}
+
+// 内圈图片、外环（或摆正的参考图）图片路径/Base64编码、匹配参数，
+// 返回内圈需顺时针旋转的角度，设置轨道长度时同时返回滑块移动距离
+func RotateMatch(innerStr, outerStr string, opts ...ddddgocr.Option) (*ddddgocr.RotateResult, error) {
+	return RotateMatchContext(context.Background(), innerStr, outerStr, opts...)
+}
+
+// 可取消的RotateMatch，ctx取消或超时后返回ctx.Err()
+func RotateMatchContext(ctx context.Context, innerStr, outerStr string, opts ...ddddgocr.Option) (*ddddgocr.RotateResult, error) {
+	innerData, err := readImageArg(innerStr, ddddgocr.RoleTarget)
+	if err != nil {
+		return nil, err
+	}
+	outerData, err := readImageArg(outerStr, ddddgocr.RoleBackground)
+	if err != nil {
+		return nil, err
+	}
+
+	return ddddgocr.RotateMatchContext(ctx, innerData, outerData, opts...)
+}
+
+// 提示条图片、背景图片路径/Base64编码、匹配参数，
+// 返回按提示条中图标顺序排列的点击位置
+func ClickMatch(promptStr, backgroundStr string, opts ...ddddgocr.Option) (*ddddgocr.ClickResult, error) {
+	return ClickMatchContext(context.Background(), promptStr, backgroundStr, opts...)
+}
+
+// 可取消的ClickMatch，ctx取消或超时后返回ctx.Err()
+func ClickMatchContext(ctx context.Context, promptStr, backgroundStr string, opts ...ddddgocr.Option) (*ddddgocr.ClickResult, error) {
+	promptData, err := readImageArg(promptStr, ddddgocr.RoleTarget)
+	if err != nil {
+		return nil, err
+	}
+	backgroundData, err := readImageArg(backgroundStr, ddddgocr.RoleBackground)
+	if err != nil {
+		return nil, err
+	}
+
+	return ddddgocr.ClickMatchContext(ctx, promptData, backgroundData, opts...)
+}
+
+// 被交换拼块的完整图片路径/Base64编码、匹配参数（网格大小由WithTileGrid指定），
+// 返回需交换的两个位置与还原图像的完整排列
+func TileSwap(imageStr string, opts ...ddddgocr.Option) (*ddddgocr.TileResult, error) {
+	return TileSwapContext(context.Background(), imageStr, opts...)
+}
+
+// 可取消的TileSwap，ctx取消或超时后返回ctx.Err()
+func TileSwapContext(ctx context.Context, imageStr string, opts ...ddddgocr.Option) (*ddddgocr.TileResult, error) {
+	imageData, err := readImageArg(imageStr, ddddgocr.RoleBackground)
+	if err != nil {
+		return nil, err
+	}
+
+	return ddddgocr.TileSwapContext(ctx, imageData, opts...)
+}
+
+// 打乱分块的拼图图片路径/Base64编码、JSON格式的CSS偏移列表、分块排列方式，
+// 返回PNG编码的还原背景，可直接传给SlideMatchWithByte
+func RestoreStrips(spriteStr, offsetsJSON string, layout ddddgocr.StripLayout) ([]byte, error) {
+	spriteData, err := readImageArg(spriteStr, ddddgocr.RoleBackground)
+	if err != nil {
+		return nil, err
+	}
+
+	return ddddgocr.RestoreStripsWithByte(spriteData, []byte(offsetsJSON), layout)
+}
+
+// 读取图片参数，存在该路径的文件时读取文件，否则按Base64解析
+func readImageArg(str string, role ddddgocr.ImageRole) ([]byte, error) {
+	if _, err := os.Stat(str); err == nil {
+		data, err := os.ReadFile(str)
 		if err != nil {
-			return nil, fmt.Errorf("解析目标图像失败: %v", err)
+			return nil, &ddddgocr.DecodeError{Image: role, Err: err}
 		}
+		return data, nil
 	}
-	_, err = os.Stat(backgroundStr)
-	if err == nil {
-		backgroundData, err = os.ReadFile(backgroundStr)
-		if err != nil {
-			return nil, fmt.Errorf("读取背景图像文件失败: %v", err)
+
+	data, err := decodeBase64Image(str)
+	if err != nil {
+		return nil, &ddddgocr.DecodeError{Image: role, Err: err}
+	}
+	return data, nil
+}
+
+// 解析Base64图片，支持data URI前缀、URL安全字母表与省略填充
+func decodeBase64Image(str string) ([]byte, error) {
+	str = strings.TrimSpace(str)
+
+	if strings.HasPrefix(str, "data:") {
+		header, payload, ok := strings.Cut(str, ",")
+		if !ok {
+			return nil, ddddgocr.ErrEmptyImage
 		}
-	} else {
-		backgroundData, err = base64.StdEncoding.DecodeString(backgroundStr)
-		if err != nil {
-			return nil, fmt.Errorf("解析背景图像失败: %v", err)
+		if !strings.HasSuffix(header, ";base64") {
+			// 非Base64的data URI为百分号编码
+			data, err := url.PathUnescape(payload)
+			if err != nil {
+				return nil, err
+			}
+			return []byte(data), nil
 		}
+		str = payload
 	}
 
-	return SlideMatchWithByte(targetData, backgroundData, matchType, matchEngine)
-}
-
-// 目标图片、背景图片、匹配方式、匹配引擎，
-// 比较模式的背景图为完整图片
-func SlideMatchWithByte(targetData, backgroundData []byte, matchType SlideMatchType, matchEngine MatchEngine) (*ddddgocr.SlideBBox, error) {
-	if matchEngine == OpenCV {
-		return slideMatchWithOpenCV(targetData, backgroundData, matchType)
-	} else {
-		switch matchType {
-		case Simple:
-			return ddddgocr.SimpleSlideMatch(targetData, backgroundData)
-		case Standard:
-			return ddddgocr.SlideMatch(targetData, backgroundData)
-		case Enhanced:
-			return ddddgocr.EnhancedSlideMatch(targetData, backgroundData)
-		case Comparison:
-			return ddddgocr.SlideComparison(targetData, backgroundData)
-		default:
-			return nil, fmt.Errorf("匹配类型错误")
+	// 去除换行等空白字符与末尾填充
+	str = strings.Map(func(r rune) rune {
+		if r == ' ' || r == '\n' || r == '\r' || r == '\t' {
+			return -1
 		}
+		return r
+	}, str)
+	str = strings.TrimRight(str, "=")
+
+	if strings.ContainsAny(str, "-_") {
+		return base64.RawURLEncoding.DecodeString(str)
 	}
+	return base64.RawStdEncoding.DecodeString(str)
 }
@@ -1,252 +1,463 @@
 package ddddgocr
 
 import (
-	"bytes"
-	"errors"
-	"fmt"
+	"context"
 	"image"
 	"image/color"
 	_ "image/gif"  // 导入GIF格式支持
 	_ "image/jpeg" // 导入JPEG格式支持
 	_ "image/png"  // 导入PNG格式支持
 	"math"
+	"sort"
+	"time"
 )
 
+// EngineName 纯Go引擎名称
+const EngineName = "default"
+
 // SlideBBox 滑块边界框结构
 type SlideBBox struct {
 	TargetY, X1, Y1, X2, Y2 int
+
+	SubX, SubY float64 // 亚像素精度的X1、Y1，未细化时与之相同
+
+	Area int // 区域的像素数，仅坑位匹配与缺口定位时有效
+}
+
+// Strategy 产生匹配结果的策略
+type Strategy string
+
+const (
+	StrategyEdge       Strategy = "edge"       // Canny边缘匹配
+	StrategyGray       Strategy = "gray"       // 灰度模板匹配
+	StrategyEdgeLow    Strategy = "edge_low"   // 低阈值边缘匹配
+	StrategyEdgeMid    Strategy = "edge_mid"   // 中阈值边缘匹配
+	StrategyDifference Strategy = "difference" // 差分寻找缺口
+	StrategySIFT       Strategy = "sift"       // SIFT特征匹配
+	StrategyORB        Strategy = "orb"        // ORB特征匹配
+	StrategyComparison Strategy = "comparison" // 双图差异比较
+	StrategyGap        Strategy = "gap"        // 仅由背景定位缺口
+	StrategyContour    Strategy = "contour"    // 轮廓形状匹配
+)
+
+// SlideResult 滑块匹配结果
+type SlideResult struct {
+	SlideBBox
+	Score     float64       // 匹配得分
+	Margin    float64       // 最佳得分与次佳得分之差
+	Strategy  Strategy      // 产生结果的策略
+	Scale     float64       // 匹配时滑块的缩放倍数，未进行尺度搜索时为1
+	Angle     float64       // 匹配时滑块顺时针旋转的角度（度），未进行角度搜索时为0
+	Agreement float64       // 增强匹配中与最终结果位置一致的策略权重占比，其余匹配方式为0
+	Engine    string        // 执行匹配的引擎
+	Elapsed   time.Duration // 匹配耗时
+
+	Candidates []SlideCandidate // 按可信度排列的候选位置，首个即最终结果
+}
+
+// 填充引擎名称与耗时
+func finishResult(result *SlideResult, start time.Time) *SlideResult {
+	if result.Scale == 0 {
+		result.Scale = 1
+	}
+	result.Engine = EngineName
+	result.Elapsed = time.Since(start)
+	return result
 }
 
 // 滑块匹配主函数
-func SlideMatch(targetImageData, backgroundImageData []byte) (*SlideBBox, error) {
+func SlideMatch(targetImageData, backgroundImageData []byte, opts ...Option) (*SlideResult, error) {
+	return SlideMatchContext(context.Background(), targetImageData, backgroundImageData, opts...)
+}
+
+// SlideMatchContext 可取消的滑块匹配
+func SlideMatchContext(ctx context.Context, targetImageData, backgroundImageData []byte, opts ...Option) (*SlideResult, error) {
+	start := time.Now()
+
 	// 解码图像
-	targetImg, _, err := image.Decode(bytes.NewReader(targetImageData))
+	targetImg, backgroundImg, err := decodeImages(targetImageData, backgroundImageData)
 	if err != nil {
-		return nil, fmt.Errorf("解码目标图像失败: %v", err)
+		return nil, err
 	}
 
-	backgroundImg, _, err := image.Decode(bytes.NewReader(backgroundImageData))
-	if err != nil {
-		return nil, fmt.Errorf("解码背景图像失败: %v", err)
-	}
+	return slideMatch(ctx, start, targetImg, backgroundImg, NewOptions(opts...))
+}
 
-	// 检查图像尺寸
-	if backgroundImg.Bounds().Dx() < targetImg.Bounds().Dx() {
-		return nil, errors.New("背景图片的宽度必须大于等于目标图片的宽度")
-	}
+// SlideMatchImage 对已解码图像进行滑块匹配
+func SlideMatchImage(ctx context.Context, targetImg, backgroundImg image.Image, opts ...Option) (*SlideResult, error) {
+	return slideMatch(ctx, time.Now(), originImage(targetImg), originImage(backgroundImg), NewOptions(opts...))
+}
 
-	if backgroundImg.Bounds().Dy() < targetImg.Bounds().Dy() {
-		return nil, errors.New("背景图片的高度必须大于等于目标图片的高度")
+// 滑块匹配流程，start为计时起点
+func slideMatch(ctx context.Context, start time.Time, targetImg, backgroundImg image.Image, o *Options) (*SlideResult, error) {
+	// 检查图像尺寸
+	if err := CheckContains(targetImg.Bounds().Size(), backgroundImg.Bounds().Size()); err != nil {
+		return nil, err
 	}
 
 	// 转换为RGBA格式
 	targetRGBA := toRGBA(targetImg)
 
 	// 裁剪透明区域
-	croppedTarget, startY, _ := cropTransparent(targetRGBA)
+	croppedTarget, startY, startX := cropTransparent(targetRGBA)
+
+	// 透明通道即滑块形状，缩放、旋转与裁剪均以形状为准
+	shape := alphaMask(croppedTarget)
+
+	// 带透明通道时按缺口特征排除干扰缺口，需要更多候选；目标图像有透明边距时滑块位置已知
+	k := o.TopK
+	if shape != nil && o.DecoyCandidates > 1 {
+		k = max(k, o.DecoyCandidates)
+	}
+	var known *image.Point
+	if croppedTarget.Bounds().Size() != targetRGBA.Bounds().Size() {
+		known = &image.Point{X: startX, Y: startY}
+	}
 
 	// 转换为灰度图
 	targetGray := rgbaToGrayScale(croppedTarget)
-	backgroundGray := toGrayScale(backgroundImg)
+	backgroundGray, err := toGrayScale(ctx, backgroundImg, o.workerCount())
+	if err != nil {
+		return nil, err
+	}
 
 	// 边缘检测
-	targetEdges := cannyEdgeDetection(targetGray, 100.0, 200.0)
-	backgroundEdges := cannyEdgeDetection(backgroundGray, 100.0, 200.0)
+	backgroundEdges, err := cannyEdgeDetection(ctx, backgroundGray, o.Canny.Low, o.Canny.High, o.workerCount())
+	if err != nil {
+		return nil, err
+	}
+
+	// 逐个缩放倍数与旋转角度匹配，未设置范围时只匹配原始尺寸与角度
+	result, err := SearchScales(o, func(scale float64) (*SlideResult, error) {
+		scaledGray, scaledShape := scalePiece(targetGray, shape, scale)
+		return SearchAngles(o, func(angle float64) (*SlideResult, error) {
+			// 旋转后重新裁剪到形状的不透明区域，结果为旋转后滑块的实际范围；各角度使用相同的掩码规则
+			pieceGray, pieceShape, offset := rotateCropped(scaledGray, scaledShape, angle)
+			var pieceMask *image.Alpha
+			if o.AlphaMask {
+				pieceMask = pieceShape
+			}
+			targetEdges, err := cannyEdgeDetection(ctx, pieceGray, o.Canny.Low, o.Canny.High, o.workerCount())
+			if err != nil {
+				return nil, err
+			}
+
+			// 搜索范围，有方向约束时只在滑块所在的行带或列带中匹配
+			tplWidth, tplHeight := targetEdges.Bounds().Dx(), targetEdges.Bounds().Dy()
+			piece := image.Pt(int(math.Round(float64(startX)*scale))+offset.X, int(math.Round(float64(startY)*scale))+offset.Y)
+			region := o.SearchRegion(backgroundEdges.Bounds(), image.Pt(tplWidth, tplHeight), &piece)
+
+			// 模板匹配
+			matchResult, err := matchTemplate(ctx, cropGray(backgroundEdges, region), targetEdges, pieceMask, o)
+			if err != nil || matchResult == nil {
+				return nil, err
+			}
+
+			// 找到最佳匹配位置
+			peaks := findPeaks(matchResult, max(k, 2), tplWidth, tplHeight)
+			refinePeaks(matchResult, peaks, o.SubPixel)
+
+			result := peakResult(peaks, tplWidth, tplHeight, startY, k, StrategyEdge)
+			result.Translate(region.Min)
+			return result, nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, ErrTemplateMatch
+	}
 
-	// 模板匹配
-	matchResult := matchTemplate(backgroundEdges, targetEdges)
-	if matchResult == nil {
-		return nil, errors.New("模板匹配失败")
+	if result.Score < o.MinScore { // 设置一个阈值来判断匹配质量
+		return nil, &LowQualityError{Score: result.Score, Threshold: o.MinScore, Strategy: StrategyEdge}
 	}
 
-	// 找到最佳匹配位置
-	maxVal, maxX, maxY, _, _, _ := findExtremes(matchResult)
+	// 综合缺口特征重新排序候选，使用低阈值边缘，淡描边的缺口在标准阈值下轮廓不完整
+	if k > o.TopK {
+		rankEdges, err := cannyEdgeDetection(ctx, backgroundGray, o.CannyLow.Low, o.CannyLow.High, o.workerCount())
+		if err != nil {
+			return nil, err
+		}
 
-	if maxVal < 0.3 { // 设置一个阈值来判断匹配质量
-		return nil, errors.New("匹配质量过低")
+		// 按结果的倍数与角度重新变换滑块形状，与产生候选的裁剪一致
+		scaledGray, scaledShape := scalePiece(targetGray, shape, result.Scale)
+		_, pieceShape, offset := rotateCropped(scaledGray, scaledShape, result.Angle)
+		var piece *image.Point
+		if known != nil {
+			piece = &image.Point{
+				X: int(math.Round(float64(known.X)*result.Scale)) + offset.X,
+				Y: int(math.Round(float64(known.Y)*result.Scale)) + offset.Y,
+			}
+		}
+		if err := RankGapCandidates(ctx, result, backgroundGray, rankEdges, pieceShape, piece, o); err != nil {
+			return nil, err
+		}
 	}
 
-	return &SlideBBox{
-		TargetY: startY,
-		X1:      maxX,
-		Y1:      maxY,
-		X2:      maxX + targetEdges.Bounds().Dx(),
-		Y2:      maxY + targetEdges.Bounds().Dy(),
-	}, nil
+	return finishResult(result, start), nil
 }
 
 // 简单滑块匹配（无透明区域裁剪）
-func SimpleSlideMatch(targetImageData, backgroundImageData []byte) (*SlideBBox, error) {
+func SimpleSlideMatch(targetImageData, backgroundImageData []byte, opts ...Option) (*SlideResult, error) {
+	return SimpleSlideMatchContext(context.Background(), targetImageData, backgroundImageData, opts...)
+}
+
+// SimpleSlideMatchContext 可取消的简单滑块匹配
+func SimpleSlideMatchContext(ctx context.Context, targetImageData, backgroundImageData []byte, opts ...Option) (*SlideResult, error) {
+	start := time.Now()
+
 	// 解码图像
-	targetImg, _, err := image.Decode(bytes.NewReader(targetImageData))
+	targetImg, backgroundImg, err := decodeImages(targetImageData, backgroundImageData)
 	if err != nil {
-		return nil, fmt.Errorf("解码目标图像失败: %v", err)
+		return nil, err
 	}
 
-	backgroundImg, _, err := image.Decode(bytes.NewReader(backgroundImageData))
-	if err != nil {
-		return nil, fmt.Errorf("解码背景图像失败: %v", err)
-	}
+	return simpleSlideMatch(ctx, start, targetImg, backgroundImg, NewOptions(opts...))
+}
 
-	// 检查图像尺寸
-	if backgroundImg.Bounds().Dx() < targetImg.Bounds().Dx() {
-		return nil, errors.New("背景图片的宽度必须大于等于目标图标的宽度")
-	}
+// SimpleSlideMatchImage 对已解码图像进行简单滑块匹配
+func SimpleSlideMatchImage(ctx context.Context, targetImg, backgroundImg image.Image, opts ...Option) (*SlideResult, error) {
+	return simpleSlideMatch(ctx, time.Now(), originImage(targetImg), originImage(backgroundImg), NewOptions(opts...))
+}
 
-	if backgroundImg.Bounds().Dy() < targetImg.Bounds().Dy() {
-		return nil, errors.New("背景图片的高度必须大于等于目标图标的高度")
+// 简单滑块匹配流程，start为计时起点
+func simpleSlideMatch(ctx context.Context, start time.Time, targetImg, backgroundImg image.Image, o *Options) (*SlideResult, error) {
+	// 检查图像尺寸
+	if err := CheckContains(targetImg.Bounds().Size(), backgroundImg.Bounds().Size()); err != nil {
+		return nil, err
 	}
 
 	// 转换为灰度图
-	targetGray := toGrayScale(targetImg)
-	backgroundGray := toGrayScale(backgroundImg)
+	targetGray, err := toGrayScale(ctx, targetImg, o.workerCount())
+	if err != nil {
+		return nil, err
+	}
+	backgroundGray, err := toGrayScale(ctx, backgroundImg, o.workerCount())
+	if err != nil {
+		return nil, err
+	}
 
 	// 边缘检测
-	targetEdges := cannyEdgeDetection(targetGray, 100.0, 200.0)
-	backgroundEdges := cannyEdgeDetection(backgroundGray, 100.0, 200.0)
-
-	// 模板匹配
-	matchResult := matchTemplate(backgroundEdges, targetEdges)
-	if matchResult == nil {
-		return nil, errors.New("模板匹配失败")
+	backgroundEdges, err := cannyEdgeDetection(ctx, backgroundGray, o.Canny.Low, o.Canny.High, o.workerCount())
+	if err != nil {
+		return nil, err
 	}
 
-	// 找到最佳匹配位置
-	maxVal, maxX, maxY, _, _, _ := findExtremes(matchResult)
+	// 逐个缩放倍数匹配，未设置尺度范围时只匹配原始尺寸
+	result, err := SearchScales(o, func(scale float64) (*SlideResult, error) {
+		scaledGray, _ := scalePiece(targetGray, nil, scale)
+		targetEdges, err := cannyEdgeDetection(ctx, scaledGray, o.Canny.Low, o.Canny.High, o.workerCount())
+		if err != nil {
+			return nil, err
+		}
+
+		// 搜索范围，未裁剪透明区域，滑块自身偏移未知
+		tplWidth, tplHeight := targetEdges.Bounds().Dx(), targetEdges.Bounds().Dy()
+		region := o.SearchRegion(backgroundEdges.Bounds(), image.Pt(tplWidth, tplHeight), nil)
+
+		// 模板匹配
+		matchResult, err := matchTemplate(ctx, cropGray(backgroundEdges, region), targetEdges, nil, o)
+		if err != nil || matchResult == nil {
+			return nil, err
+		}
+
+		// 找到最佳匹配位置
+		peaks := findPeaks(matchResult, max(o.TopK, 2), tplWidth, tplHeight)
+		refinePeaks(matchResult, peaks, o.SubPixel)
+
+		result := peakResult(peaks, tplWidth, tplHeight, 0, o.TopK, StrategyEdge)
+		result.Translate(region.Min)
+		return result, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, ErrTemplateMatch
+	}
 
-	if maxVal < 0.3 { // 设置一个阈值来判断匹配质量
-		return nil, errors.New("匹配质量过低")
+	if result.Score < o.MinScore { // 设置一个阈值来判断匹配质量
+		return nil, &LowQualityError{Score: result.Score, Threshold: o.MinScore, Strategy: StrategyEdge}
 	}
 
-	return &SlideBBox{
-		TargetY: 0,
-		X1:      maxX,
-		Y1:      maxY,
-		X2:      maxX + targetEdges.Bounds().Dx(),
-		Y2:      maxY + targetEdges.Bounds().Dy(),
-	}, nil
+	return finishResult(result, start), nil
+}
+
+// EnhancedSlideMatch 增强版滑块匹配
+func EnhancedSlideMatch(targetImageData, backgroundImageData []byte, opts ...Option) (*SlideResult, error) {
+	return EnhancedSlideMatchContext(context.Background(), targetImageData, backgroundImageData, opts...)
 }
 
-func EnhancedSlideMatch(targetImageData, backgroundImageData []byte) (*SlideBBox, error) {
+// EnhancedSlideMatchContext 可取消的增强版滑块匹配
+func EnhancedSlideMatchContext(ctx context.Context, targetImageData, backgroundImageData []byte, opts ...Option) (*SlideResult, error) {
+	start := time.Now()
+
 	// 解码图像
-	targetImg, _, err := image.Decode(bytes.NewReader(targetImageData))
+	targetImg, backgroundImg, err := decodeImages(targetImageData, backgroundImageData)
 	if err != nil {
-		return nil, fmt.Errorf("解码目标图像失败: %v", err)
+		return nil, err
 	}
 
-	backgroundImg, _, err := image.Decode(bytes.NewReader(backgroundImageData))
-	if err != nil {
-		return nil, fmt.Errorf("解码背景图像失败: %v", err)
-	}
+	return enhancedSlideMatch(ctx, start, targetImg, backgroundImg, NewOptions(opts...))
+}
 
-	// 检查图像尺寸
-	if backgroundImg.Bounds().Dx() < targetImg.Bounds().Dx() {
-		return nil, errors.New("背景图片的宽度必须大于等于目标图片的宽度")
-	}
+// EnhancedSlideMatchImage 对已解码图像进行增强版滑块匹配
+func EnhancedSlideMatchImage(ctx context.Context, targetImg, backgroundImg image.Image, opts ...Option) (*SlideResult, error) {
+	return enhancedSlideMatch(ctx, time.Now(), originImage(targetImg), originImage(backgroundImg), NewOptions(opts...))
+}
 
-	if backgroundImg.Bounds().Dy() < targetImg.Bounds().Dy() {
-		return nil, errors.New("背景图片的高度必须大于等于目标图片的高度")
+// 增强版滑块匹配流程，start为计时起点
+func enhancedSlideMatch(ctx context.Context, start time.Time, targetImg, backgroundImg image.Image, o *Options) (*SlideResult, error) {
+	// 检查图像尺寸
+	if err := CheckContains(targetImg.Bounds().Size(), backgroundImg.Bounds().Size()); err != nil {
+		return nil, err
 	}
 
 	// 策略1: 直接灰度匹配（不进行边缘检测）
-	targetGray := toGrayScale(targetImg)
-	backgroundGray := toGrayScale(backgroundImg)
+	targetGray, err := toGrayScale(ctx, targetImg, o.workerCount())
+	if err != nil {
+		return nil, err
+	}
+	backgroundGray, err := toGrayScale(ctx, backgroundImg, o.workerCount())
+	if err != nil {
+		return nil, err
+	}
 
 	// 如果是RGBA图像，先处理透明区域
 	var croppedTarget *image.Gray
+	var mask, shape *image.Alpha
+	var piece *image.Point
 	var startY int
 	if _, ok := targetImg.(*image.RGBA); ok || hasTransparency(targetImg) {
 		targetRGBA := toRGBA(targetImg)
-		cropped, sy, _ := cropTransparent(targetRGBA)
+		cropped, sy, sx := cropTransparent(targetRGBA)
 		croppedTarget = rgbaToGrayScale(cropped)
 		startY = sy
+		piece = &image.Point{X: sx, Y: sy}
+		shape = alphaMask(cropped)
+		if o.AlphaMask {
+			mask = shape
+		}
 	} else {
 		croppedTarget = targetGray
 		startY = 0
 	}
 
-	results := make([]*SlideBBox, 0)
+	// 搜索范围，各策略的模板尺寸相同，共用同一区域
+	tplWidth, tplHeight := croppedTarget.Bounds().Dx(), croppedTarget.Bounds().Dy()
+	region := o.SearchRegion(backgroundGray.Bounds(), image.Pt(tplWidth, tplHeight), piece)
+
+	results := make([]*SlideResult, 0)
+	// 灰度与低阈值边缘匹配的峰值，作为轮廓匹配的搜索窗口
+	anchors := make([]image.Rectangle, 0)
 
 	// 策略1: 直接灰度模板匹配
-	matchResult1 := matchTemplate(backgroundGray, croppedTarget)
+	matchResult1, err := matchTemplate(ctx, cropGray(backgroundGray, region), croppedTarget, mask, o)
+	if err != nil {
+		return nil, err
+	}
 	if matchResult1 != nil {
-		maxVal, maxX, maxY, _, _, _ := findExtremes(matchResult1)
-		// fmt.Printf("策略1 - 灰度匹配: 最大值=%.4f, 位置=(%d, %d)\n", maxVal, maxX, maxY)
-		if maxVal > 0.6 {
-			results = append(results, &SlideBBox{
-				TargetY: startY,
-				X1:      maxX,
-				Y1:      maxY,
-				X2:      maxX + croppedTarget.Bounds().Dx(),
-				Y2:      maxY + croppedTarget.Bounds().Dy(),
-			})
+		peaks := findPeaks(matchResult1, max(o.TopK, 2), tplWidth, tplHeight)
+		refinePeaks(matchResult1, peaks, o.SubPixel)
+		anchors = append(anchors, peakBoxes(peaks, tplWidth, tplHeight)...)
+		if peaks[0].score > o.GrayMinScore {
+			results = append(results, peakResult(peaks, tplWidth, tplHeight, startY, o.TopK, StrategyGray))
 		}
 	}
 
 	// 策略2: 边缘检测匹配（低阈值）
-	targetEdges1 := cannyEdgeDetection(croppedTarget, 30.0, 80.0)
-	backgroundEdges1 := cannyEdgeDetection(backgroundGray, 30.0, 80.0)
-	matchResult2 := matchTemplate(backgroundEdges1, targetEdges1)
+	targetEdges1, err := cannyEdgeDetection(ctx, croppedTarget, o.CannyLow.Low, o.CannyLow.High, o.workerCount())
+	if err != nil {
+		return nil, err
+	}
+	backgroundEdges1, err := cannyEdgeDetection(ctx, backgroundGray, o.CannyLow.Low, o.CannyLow.High, o.workerCount())
+	if err != nil {
+		return nil, err
+	}
+	matchResult2, err := matchTemplate(ctx, cropGray(backgroundEdges1, region), targetEdges1, mask, o)
+	if err != nil {
+		return nil, err
+	}
 	if matchResult2 != nil {
-		maxVal, maxX, maxY, _, _, _ := findExtremes(matchResult2)
-		// fmt.Printf("策略2 - 低阈值边缘: 最大值=%.4f, 位置=(%d, %d)\n", maxVal, maxX, maxY)
-		if maxVal > 0.3 {
-			results = append(results, &SlideBBox{
-				TargetY: startY,
-				X1:      maxX,
-				Y1:      maxY,
-				X2:      maxX + targetEdges1.Bounds().Dx(),
-				Y2:      maxY + targetEdges1.Bounds().Dy(),
-			})
+		peaks := findPeaks(matchResult2, max(o.TopK, 2), tplWidth, tplHeight)
+		refinePeaks(matchResult2, peaks, o.SubPixel)
+		anchors = append(anchors, peakBoxes(peaks, tplWidth, tplHeight)...)
+		if peaks[0].score > o.EdgeLowMinScore {
+			results = append(results, peakResult(peaks, tplWidth, tplHeight, startY, o.TopK, StrategyEdgeLow))
 		}
 	}
 
 	// 策略3: 边缘检测匹配（中等阈值）
-	targetEdges2 := cannyEdgeDetection(croppedTarget, 50.0, 150.0)
-	backgroundEdges2 := cannyEdgeDetection(backgroundGray, 50.0, 150.0)
-	matchResult3 := matchTemplate(backgroundEdges2, targetEdges2)
+	targetEdges2, err := cannyEdgeDetection(ctx, croppedTarget, o.CannyMid.Low, o.CannyMid.High, o.workerCount())
+	if err != nil {
+		return nil, err
+	}
+	backgroundEdges2, err := cannyEdgeDetection(ctx, backgroundGray, o.CannyMid.Low, o.CannyMid.High, o.workerCount())
+	if err != nil {
+		return nil, err
+	}
+	matchResult3, err := matchTemplate(ctx, cropGray(backgroundEdges2, region), targetEdges2, mask, o)
+	if err != nil {
+		return nil, err
+	}
 	if matchResult3 != nil {
-		maxVal, maxX, maxY, _, _, _ := findExtremes(matchResult3)
-		// fmt.Printf("策略3 - 中阈值边缘: 最大值=%.4f, 位置=(%d, %d)\n", maxVal, maxX, maxY)
-		if maxVal > 0.2 {
-			results = append(results, &SlideBBox{
-				TargetY: startY,
-				X1:      maxX,
-				Y1:      maxY,
-				X2:      maxX + targetEdges2.Bounds().Dx(),
-				Y2:      maxY + targetEdges2.Bounds().Dy(),
-			})
+		peaks := findPeaks(matchResult3, max(o.TopK, 2), tplWidth, tplHeight)
+		refinePeaks(matchResult3, peaks, o.SubPixel)
+		if peaks[0].score > o.EdgeMidMinScore {
+			results = append(results, peakResult(peaks, tplWidth, tplHeight, startY, o.TopK, StrategyEdgeMid))
+		}
+	}
+
+	// 策略4: ORB特征匹配（模板策略均未采纳时）
+	if len(results) == 0 {
+		orbResult, err := orbFeatureMatch(ctx, cropGray(backgroundGray, region), croppedTarget, mask, startY, o)
+		if err != nil {
+			return nil, err
+		}
+		if orbResult != nil {
+			results = append(results, orbResult)
 		}
 	}
 
-	// 策略4: 差分匹配（寻找缺口）
-	diffResult := findSlotByDifference(backgroundGray, croppedTarget)
+	// 策略5: 轮廓形状匹配（滑块带透明通道时），搜索窗口另加滑块轮廓的匹配峰值
+	if shape != nil {
+		searchEdges := cropGray(backgroundEdges1, region)
+		outline, err := outlineAnchors(ctx, searchEdges, shape, contourAnchors, o)
+		if err != nil {
+			return nil, err
+		}
+		contourResult, err := MatchContour(ctx, searchEdges, shape, append(anchors, outline...), startY, o)
+		if err != nil {
+			return nil, err
+		}
+		if contourResult != nil && contourResult.Score >= o.ContourMinScore {
+			results = append(results, contourResult)
+		}
+	}
+
+	// 策略6: 差分匹配（寻找缺口）
+	diffResult, err := FindSlotByDifference(ctx, cropGray(backgroundGray, region), croppedTarget, o)
+	if err != nil {
+		return nil, err
+	}
 	if diffResult != nil {
-		// fmt.Printf("策略4 - 差分匹配: 位置=(%d, %d)\n", diffResult.X1, diffResult.Y1)
 		results = append(results, diffResult)
 	}
 
 	if len(results) == 0 {
-		return nil, errors.New("所有匹配策略都失败了")
+		return nil, ErrNoMatch
 	}
 
-	// 选择最可信的结果（优先选择X1 > 0的结果）
-	var bestResult *SlideBBox
+	// 平移回背景坐标
 	for _, result := range results {
-		if result.X1 > 0 {
-			if bestResult == nil || result.X1 < bestResult.X1 {
-				bestResult = result
-			}
-		}
+		result.Translate(region.Min)
 	}
 
-	// 如果没有X1 > 0的结果，选择第一个
-	if bestResult == nil {
-		bestResult = results[0]
-	}
+	// 融合各策略的结果
+	bestResult := FuseResults(results, o)
 
-	// fmt.Printf("最终选择结果: X1=%d, Y1=%d\n", bestResult.X1, bestResult.Y1)
-	return bestResult, nil
+	return finishResult(bestResult, start), nil
 }
 
 // 检查图像是否有透明度
@@ -259,8 +470,9 @@ func hasTransparency(img image.Image) bool {
 	}
 }
 
-// 通过差分方法寻找滑块缺口
-func findSlotByDifference(background, target *image.Gray) *SlideBBox {
+// FindSlotByDifference 通过差分方法寻找滑块缺口：按列统计垂直梯度，取强度峰值为缺口左边缘，
+// 再逐行比较滑块与背景确定Y位置；background、target为灰度图，两种引擎的增强匹配共用
+func FindSlotByDifference(ctx context.Context, background, target *image.Gray, o *Options) (*SlideResult, error) {
 	bgBounds := background.Bounds()
 	tgtBounds := target.Bounds()
 
@@ -270,13 +482,16 @@ func findSlotByDifference(background, target *image.Gray) *SlideBBox {
 	tgtHeight := tgtBounds.Dy()
 
 	if bgWidth < tgtWidth || bgHeight < tgtHeight {
-		return nil
+		return nil, nil
 	}
 
 	// 计算每一列的垂直边缘强度
 	columnEdges := make([]float64, bgWidth)
 
 	for x := 1; x < bgWidth-1; x++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		var edgeStrength float64
 		for y := 1; y < bgHeight-1; y++ {
 			// 计算垂直梯度
@@ -288,41 +503,76 @@ func findSlotByDifference(background, target *image.Gray) *SlideBBox {
 		columnEdges[x] = edgeStrength / float64(bgHeight-2)
 	}
 
-	// 寻找垂直边缘强度的峰值（可能是滑块缺口的左边缘）
-	maxEdge := 0.0
-	maxX := 0
-
-	// 寻找合适区域内的最大边缘强度
+	// 寻找合适区域内的垂直边缘强度峰值（可能是滑块缺口的左边缘）
 	searchStart := tgtWidth / 2
 	searchEnd := bgWidth - tgtWidth - tgtWidth/2
+	if searchEnd <= searchStart {
+		return nil, nil
+	}
+	edgeFloor, k := o.SlotEdgeFloor, o.TopK
+	profile := [][]float64{columnEdges[searchStart:searchEnd]}
+	peaks := findPeaks(profile, max(k, 2), tgtWidth, 1)
+	refinePeaks(profile, peaks, o.SubPixel)
 
-	for x := searchStart; x < searchEnd; x++ {
-		if columnEdges[x] > maxEdge {
-			maxEdge = columnEdges[x]
-			maxX = x
+	if peaks[0].score <= edgeFloor { // 设置一个边缘强度阈值
+		return nil, nil
+	}
+
+	// 得分为峰值高出搜索范围内列边缘强度均值的比例，与相关系数同在[0, 1]内，
+	// 缺口边缘远强于背景纹理时接近1，与纹理相当时接近0
+	var mean float64
+	for _, v := range profile[0] {
+		mean += v
+	}
+	mean /= float64(len(profile[0]))
+	prominence := func(p peak) float64 {
+		if p.score <= 0 {
+			return 0
 		}
+		return math.Max(0, (p.score-mean)/p.score)
 	}
 
-	// fmt.Printf("差分匹配 - 最大边缘强度: %.2f, 位置: %d\n", maxEdge, maxX)
+	candidates := make([]SlideCandidate, 0, k)
+	for i := 0; i < len(peaks) && i < k && peaks[i].score > edgeFloor; i++ {
+		x := searchStart + peaks[i].x
 
-	if maxEdge > 10.0 { // 设置一个边缘强度阈值
 		// 寻找最佳的Y位置
-		bestY := findBestYPosition(background, target, maxX)
-
-		return &SlideBBox{
-			TargetY: 0,
-			X1:      maxX,
-			Y1:      bestY,
-			X2:      maxX + tgtWidth,
-			Y2:      bestY + tgtHeight,
+		bestY, err := findBestYPosition(ctx, background, target, x)
+		if err != nil {
+			return nil, err
 		}
-	}
 
-	return nil
+		candidates = append(candidates, SlideCandidate{
+			SlideBBox: SlideBBox{
+				TargetY: 0,
+				X1:      x,
+				Y1:      bestY,
+				X2:      x + tgtWidth,
+				Y2:      bestY + tgtHeight,
+				SubX:    float64(searchStart) + peaks[i].subX,
+				SubY:    float64(bestY),
+			},
+			Score:    prominence(peaks[i]),
+			Strategy: StrategyDifference,
+		})
+	}
+
+	second := 0.0
+	if len(peaks) > 1 {
+		second = prominence(peaks[1])
+	}
+
+	return &SlideResult{
+		SlideBBox:  candidates[0].SlideBBox,
+		Score:      candidates[0].Score,
+		Margin:     candidates[0].Score - second,
+		Strategy:   StrategyDifference,
+		Candidates: candidates,
+	}, nil
 }
 
 // 在给定X位置寻找最佳Y位置
-func findBestYPosition(background, target *image.Gray, x int) int {
+func findBestYPosition(ctx context.Context, background, target *image.Gray, x int) (int, error) {
 	bgBounds := background.Bounds()
 	tgtBounds := target.Bounds()
 
@@ -334,6 +584,10 @@ func findBestYPosition(background, target *image.Gray, x int) int {
 	bestScore := -1.0
 
 	for y := 0; y <= bgHeight-tgtHeight; y++ {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+
 		// 计算在这个位置的匹配分数
 		var score float64
 		var count int
@@ -360,27 +614,38 @@ func findBestYPosition(background, target *image.Gray, x int) int {
 		}
 	}
 
-	return bestY
+	return bestY, nil
 }
 
 // SlideComparison 坑位匹配
 // 通过比较两张相同尺寸的图片，找出差异区域来定位坑位位置
-func SlideComparison(targetImageData, backgroundImageData []byte) (*SlideBBox, error) {
+func SlideComparison(targetImageData, backgroundImageData []byte, opts ...Option) (*SlideResult, error) {
+	return SlideComparisonContext(context.Background(), targetImageData, backgroundImageData, opts...)
+}
+
+// SlideComparisonContext 可取消的坑位匹配
+func SlideComparisonContext(ctx context.Context, targetImageData, backgroundImageData []byte, opts ...Option) (*SlideResult, error) {
+	start := time.Now()
+
 	// 解码图像
-	targetImg, _, err := image.Decode(bytes.NewReader(targetImageData))
+	targetImg, backgroundImg, err := decodeImages(targetImageData, backgroundImageData)
 	if err != nil {
-		return nil, fmt.Errorf("解码目标图像失败: %v", err)
+		return nil, err
 	}
 
-	backgroundImg, _, err := image.Decode(bytes.NewReader(backgroundImageData))
-	if err != nil {
-		return nil, fmt.Errorf("解码背景图像失败: %v", err)
-	}
+	return slideComparison(ctx, start, targetImg, backgroundImg, NewOptions(opts...))
+}
 
+// SlideComparisonImage 对已解码图像进行坑位匹配
+func SlideComparisonImage(ctx context.Context, targetImg, backgroundImg image.Image, opts ...Option) (*SlideResult, error) {
+	return slideComparison(ctx, time.Now(), originImage(targetImg), originImage(backgroundImg), NewOptions(opts...))
+}
+
+// 坑位匹配流程，start为计时起点
+func slideComparison(ctx context.Context, start time.Time, targetImg, backgroundImg image.Image, o *Options) (*SlideResult, error) {
 	// 检查图像尺寸是否相等
-	if targetImg.Bounds().Dx() != backgroundImg.Bounds().Dx() ||
-		targetImg.Bounds().Dy() != backgroundImg.Bounds().Dy() {
-		return nil, errors.New("图片尺寸不相等")
+	if err := CheckEqual(targetImg.Bounds().Size(), backgroundImg.Bounds().Size()); err != nil {
+		return nil, err
 	}
 
 	width := targetImg.Bounds().Dx()
@@ -391,6 +656,9 @@ func SlideComparison(targetImageData, backgroundImageData []byte) (*SlideBBox, e
 
 	// 计算像素差异
 	for y := range height {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		for x := range width {
 			// 获取目标图像和背景图像的像素值
 			targetR, targetG, targetB, _ := targetImg.At(x, y).RGBA()
@@ -406,8 +674,8 @@ func SlideComparison(targetImageData, backgroundImageData []byte) (*SlideBBox, e
 			diffB := absDiff(tB, bB)
 			avgDiff := (uint16(diffR) + uint16(diffG) + uint16(diffB)) / 3
 
-			// 如果差异大于80，设为白色(255)，否则为黑色(0)
-			if avgDiff > 80 {
+			// 如果差异大于阈值，设为白色(255)，否则为黑色(0)
+			if int(avgDiff) > o.DiffThreshold {
 				diffImage.Set(x, y, color.Gray{Y: 255})
 			} else {
 				diffImage.Set(x, y, color.Gray{Y: 0})
@@ -415,39 +683,88 @@ func SlideComparison(targetImageData, backgroundImageData []byte) (*SlideBBox, e
 		}
 	}
 
-	var startX, startY int = 0, 0
+	// 开运算去除JPEG噪点等零散差异
+	if o.DiffOpenSize > 1 {
+		opened, err := morphOpen(ctx, diffImage, o.DiffOpenSize, o.workerCount())
+		if err != nil {
+			return nil, err
+		}
+		diffImage = opened
+	}
 
-	// 按列扫描寻找差异区域
-	for x := range width {
-		count := 0
+	// 差异图中的8连通区域
+	regions, err := labelRegions(ctx, width, height, true, func(i int) bool {
+		return diffImage.Pix[i] != 0
+	})
+	if err != nil {
+		return nil, err
+	}
+	components := make([]DiffComponent, len(regions))
+	for i, region := range regions {
+		components[i] = DiffComponent{Bounds: region.bounds, Area: len(region.pixels)}
+	}
 
-		for y := range height {
-			pixel := diffImage.GrayAt(x, y)
+	result, err := ComparisonResult(components, o)
+	if err != nil {
+		return nil, err
+	}
+	return finishResult(result, start), nil
+}
 
-			// 如果像素不是黑色（即存在差异）
-			if pixel.Y != 0 {
-				count++
-			}
+// DiffComponent 差异图中的连通区域
+type DiffComponent struct {
+	Bounds image.Rectangle
+	Area   int // 像素数
+}
 
-			// 如果连续发现5个差异像素且还未设置startY
-			if count >= 5 && startY == 0 {
-				if y >= 5 {
-					startY = int(y - 5)
-				} else {
-					startY = 0
-				}
-			}
+// ComparisonResult 由去噪后差异图的连通区域构造坑位匹配结果，宽高均不小于DiffRunLength的区域为候选，
+// 得分为面积占比与形状规整度（填充率×长宽比）之积，得分最高者为坑位；没有候选时返回ErrNoMatch
+func ComparisonResult(components []DiffComponent, o *Options) (*SlideResult, error) {
+	kept := make([]DiffComponent, 0, len(components))
+	total := 0
+	for _, c := range components {
+		if c.Bounds.Dx() >= o.DiffRunLength && c.Bounds.Dy() >= o.DiffRunLength && c.Area > 0 {
+			kept = append(kept, c)
+			total += c.Area
 		}
+	}
 
-		// 如果该列有足够的差异像素，说明找到了坑位的起始位置
-		if count >= 5 {
-			startX = int(x + 2) // 稍微向右偏移2个像素
-			break
+	if len(kept) == 0 {
+		return nil, ErrNoMatch
+	}
+
+	candidates := make([]SlideCandidate, len(kept))
+	for i, c := range kept {
+		w, h := c.Bounds.Dx(), c.Bounds.Dy()
+		fill := float64(c.Area) / float64(w*h)
+		regularity := fill * float64(min(w, h)) / float64(max(w, h))
+		candidates[i] = SlideCandidate{
+			SlideBBox: SlideBBox{
+				X1:   c.Bounds.Min.X,
+				Y1:   c.Bounds.Min.Y,
+				X2:   c.Bounds.Max.X,
+				Y2:   c.Bounds.Max.Y,
+				SubX: float64(c.Bounds.Min.X),
+				SubY: float64(c.Bounds.Min.Y),
+				Area: c.Area,
+			},
+			Score:    float64(c.Area) / float64(total) * regularity,
+			Strategy: StrategyComparison,
 		}
 	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
 
-	return &SlideBBox{
-		X1: startX,
-		Y1: startY,
-	}, nil
+	result := &SlideResult{
+		SlideBBox:  candidates[0].SlideBBox,
+		Score:      candidates[0].Score,
+		Margin:     candidates[0].Score,
+		Strategy:   StrategyComparison,
+		Candidates: candidates[:min(o.TopK, len(candidates))],
+	}
+	if len(candidates) > 1 {
+		result.Margin -= candidates[1].Score
+	}
+	return result, nil
 }
@@ -0,0 +1,151 @@
+package ddddgocr
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// 拼图形状：44×44的方块，上方与右侧带半圆凸起，左侧有半圆凹口；坐标相对方块左上角，
+// 凸起超出方块，外接矩形的四角透明
+func inJigsaw(x, y int) bool {
+	const size, knob = 44, 7
+	near := func(cx, cy int) bool {
+		return (x-cx)*(x-cx)+(y-cy)*(y-cy) <= knob*knob
+	}
+	if near(size/2, -1) || near(size, size/2) {
+		return true
+	}
+	return x >= 0 && x < size && y >= 0 && y < size && !near(-1, size/2)
+}
+
+// 随机块纹理背景，缺口为绕方块中心顺时针旋转degrees度的拼图形状，区域变暗；
+// 目标图像与背景等高，滑块从缺口处切出后转回原始角度，保留透明边距；gap为缺口的外接矩形
+func jigsawScene(seed int64, gapX, gapY int, degrees float64) (target, background *image.RGBA, gap image.Rectangle) {
+	rng := rand.New(rand.NewSource(seed))
+	blocks := make([]uint8, 60*30)
+	for i := range blocks {
+		blocks[i] = uint8(70 + 130*rng.Intn(2))
+	}
+	texture := func(x, y int) uint8 {
+		return blocks[y/6*60+x/6]
+	}
+
+	// 缺口中心为方块中心，像素中心逆旋转回拼图坐标
+	sin, cos := math.Sincos(degrees * math.Pi / 180)
+	cx, cy := float64(gapX+22), float64(gapY+22)
+	background = image.NewRGBA(image.Rect(0, 0, 340, 160))
+	for y := range 160 {
+		for x := range 340 {
+			v := texture(x, y)
+			dx, dy := float64(x)+0.5-cx, float64(y)+0.5-cy
+			if inJigsaw(int(math.Floor(cos*dx+sin*dy+22)), int(math.Floor(-sin*dx+cos*dy+22))) {
+				v -= 50
+				gap = gap.Union(image.Rect(x, y, x+1, y+1))
+			}
+			background.SetRGBA(x, y, color.RGBA{R: v, G: v, B: v, A: 255})
+		}
+	}
+
+	// 滑块在目标图像中位于(4, gapY)，凸起向上、向右延伸
+	target = image.NewRGBA(image.Rect(0, 0, 64, 160))
+	for y := range 160 {
+		for x := range 64 {
+			if inJigsaw(x-4, y-gapY) {
+				sx, sy := float64(x-4)+0.5-22, float64(y-gapY)+0.5-22
+				v := texture(int(cx+cos*sx-sin*sy), int(cy+sin*sx+cos*sy))
+				target.SetRGBA(x, y, color.RGBA{R: v, G: v, B: v, A: 255})
+			}
+		}
+	}
+	return target, background, gap
+}
+
+// 默认参数下透明角落不参与相关计算，滑块外接矩形的左上角对准缺口外接矩形的左上角
+func TestSlideMatchJigsaw(t *testing.T) {
+	for _, gap := range []image.Point{{200, 50}, {120, 90}, {260, 30}} {
+		target, background, _ := jigsawScene(int64(gap.X), gap.X, gap.Y, 0)
+		result, err := SlideMatchImage(context.Background(), target, background)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 外接矩形从凸起的上沿开始，左沿即方块左沿
+		wantX, wantY := gap.X, gap.Y-8
+		if abs(result.X1-wantX) > 1 || abs(result.Y1-wantY) > 1 {
+			t.Errorf("gap %v: got (%d, %d), want (%d, %d)", gap, result.X1, result.Y1, wantX, wantY)
+		}
+	}
+}
+
+// 缺口相对滑块旋转数度时，角度搜索得到该角度，结果为旋转后形状的外接矩形；
+// 不使用掩码时同样按形状旋转与裁剪，只是透明像素参与相关计算，得分较低
+func TestSlideMatchRotated(t *testing.T) {
+	tests := []struct {
+		degrees float64
+		opts    []Option
+	}{
+		{6, nil},
+		{-5, nil},
+		{6, []Option{WithAlphaMask(false), WithMinScore(0.2)}},
+		{-5, []Option{WithAlphaMask(false), WithMinScore(0.2)}},
+	}
+	for _, tt := range tests {
+		target, background, gap := jigsawScene(7, 180, 50, tt.degrees)
+		result, err := SlideMatchImage(context.Background(), target, background, append(tt.opts, WithAngles(8, 4))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(result.Angle-tt.degrees) > 1 {
+			t.Errorf("%v° (%d options): Angle = %v", tt.degrees, len(tt.opts), result.Angle)
+		}
+		got := image.Rect(result.X1, result.Y1, result.X2, result.Y2)
+		if abs(got.Min.X-gap.Min.X) > 2 || abs(got.Min.Y-gap.Min.Y) > 2 || abs(got.Max.X-gap.Max.X) > 2 || abs(got.Max.Y-gap.Max.Y) > 2 {
+			t.Errorf("%v° (%d options): bbox = %v, want %v", tt.degrees, len(tt.opts), got, gap)
+		}
+	}
+}
+
+// 两图只在缺口处与零散噪点处不同，噪点贴在缺口边上时不去噪会并入缺口
+func TestSlideComparisonNoise(t *testing.T) {
+	const width, height = 200, 120
+	gap := image.Rect(120, 40, 162, 82)
+	rng := rand.New(rand.NewSource(3))
+	target := image.NewRGBA(image.Rect(0, 0, width, height))
+	background := image.NewRGBA(target.Bounds())
+	noisy := 0
+	for y := range height {
+		for x := range width {
+			v := uint8(80 + 40*math.Sin(float64(x)/13+float64(y)/19))
+			background.SetRGBA(x, y, color.RGBA{R: v, G: v, B: v, A: 255})
+			switch {
+			case image.Pt(x, y).In(gap):
+				v += 120
+			case rng.Intn(50) == 0:
+				v += 100
+				noisy++
+			}
+			target.SetRGBA(x, y, color.RGBA{R: v, G: v, B: v, A: 255})
+		}
+	}
+
+	result, err := SlideComparisonImage(context.Background(), target, background)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := SlideBBox{X1: gap.Min.X, Y1: gap.Min.Y, X2: gap.Max.X, Y2: gap.Max.Y, SubX: float64(gap.Min.X), SubY: float64(gap.Min.Y), Area: gap.Dx() * gap.Dy()}
+	if result.SlideBBox != want {
+		t.Errorf("bbox = %+v, want %+v (%d noisy pixels)", result.SlideBBox, want, noisy)
+	}
+
+	// 不去噪时贴边的噪点并入缺口
+	raw, err := SlideComparisonImage(context.Background(), target, background, WithDiffOpening(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raw.SlideBBox == want {
+		t.Errorf("bbox without opening = %+v, want noise merged into the gap", raw.SlideBBox)
+	}
+}
@@ -0,0 +1,159 @@
+package ddddgocr
+
+import (
+	"image"
+	"sort"
+)
+
+// SlideCandidate 候选匹配位置
+type SlideCandidate struct {
+	SlideBBox
+	Score    float64  // 候选得分
+	Strategy Strategy // 产生候选的策略
+}
+
+// 相关图中的峰值
+type peak struct {
+	x, y       int
+	score      float64
+	subX, subY float64 // 亚像素细化后的位置
+}
+
+// 非极大值抑制查找前k个峰值，与已选峰值横向距离小于radiusX且纵向距离小于radiusY的位置被抑制
+func findPeaks(matrix [][]float64, k, radiusX, radiusY int) []peak {
+	peaks := make([]peak, 0, k)
+	for len(peaks) < k {
+		best := peak{x: -1}
+		for y := range matrix {
+			for x, v := range matrix[y] {
+				if (best.x < 0 || v > best.score) && !nearPeak(peaks, x, y, radiusX, radiusY) {
+					best = peak{x: x, y: y, score: v, subX: float64(x), subY: float64(y)}
+				}
+			}
+		}
+		if best.x < 0 {
+			break
+		}
+		peaks = append(peaks, best)
+	}
+	return peaks
+}
+
+// 在相关图上对各峰值做亚像素细化
+func refinePeaks(matrix [][]float64, peaks []peak, mode SubPixelMode) {
+	if mode == SubPixelNone || len(matrix) == 0 {
+		return
+	}
+
+	at := func(x, y int) float64 {
+		return matrix[y][x]
+	}
+	for i := range peaks {
+		peaks[i].subX, peaks[i].subY = RefinePeak(at, peaks[i].x, peaks[i].y, len(matrix[0]), len(matrix), mode)
+	}
+}
+
+// 判断位置是否落在已选峰值的抑制范围内
+func nearPeak(peaks []peak, x, y, radiusX, radiusY int) bool {
+	for _, p := range peaks {
+		if x > p.x-radiusX && x < p.x+radiusX && y > p.y-radiusY && y < p.y+radiusY {
+			return true
+		}
+	}
+	return false
+}
+
+// 峰值处模板覆盖的区域，用作轮廓匹配的搜索窗口
+func peakBoxes(peaks []peak, width, height int) []image.Rectangle {
+	boxes := make([]image.Rectangle, len(peaks))
+	for i, p := range peaks {
+		boxes[i] = image.Rect(p.x, p.y, p.x+width, p.y+height)
+	}
+	return boxes
+}
+
+// 由峰值构造匹配结果，候选数量不超过k
+func peakResult(peaks []peak, width, height, targetY, k int, strategy Strategy) *SlideResult {
+	best := peaks[0]
+	second := 0.0
+	if len(peaks) > 1 && peaks[1].score > 0 {
+		second = peaks[1].score
+	}
+
+	candidates := make([]SlideCandidate, 0, k)
+	for i := 0; i < len(peaks) && i < k; i++ {
+		candidates = append(candidates, SlideCandidate{
+			SlideBBox: SlideBBox{
+				TargetY: targetY,
+				X1:      peaks[i].x,
+				Y1:      peaks[i].y,
+				X2:      peaks[i].x + width,
+				Y2:      peaks[i].y + height,
+				SubX:    peaks[i].subX,
+				SubY:    peaks[i].subY,
+			},
+			Score:    peaks[i].score,
+			Strategy: strategy,
+		})
+	}
+
+	return &SlideResult{
+		SlideBBox:  candidates[0].SlideBBox,
+		Score:      best.score,
+		Margin:     best.score - second,
+		Strategy:   strategy,
+		Candidates: candidates,
+	}
+}
+
+// RankCandidates 以best为首，其余候选按得分降序排列，
+// 与已保留候选重叠（左上角距离小于框宽高）的被抑制，最多保留k个
+func RankCandidates(best SlideCandidate, candidates []SlideCandidate, k int) []SlideCandidate {
+	sorted := make([]SlideCandidate, len(candidates))
+	copy(sorted, candidates)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Score > sorted[j].Score
+	})
+
+	ranked := []SlideCandidate{best}
+	for _, c := range sorted {
+		if len(ranked) >= k {
+			break
+		}
+		overlapped := false
+		for _, r := range ranked {
+			w, h := r.X2-r.X1, r.Y2-r.Y1
+			if c.X1 > r.X1-w && c.X1 < r.X1+w && c.Y1 > r.Y1-h && c.Y1 < r.Y1+h {
+				overlapped = true
+				break
+			}
+		}
+		if !overlapped {
+			ranked = append(ranked, c)
+		}
+	}
+	return ranked
+}
+
+// Translate 平移结果及其候选的边界框，用于将在背景子区域中得到的结果换算回背景坐标
+func (r *SlideResult) Translate(delta image.Point) {
+	if delta == (image.Point{}) {
+		return
+	}
+
+	r.SlideBBox = r.SlideBBox.translate(delta)
+	for i := range r.Candidates {
+		r.Candidates[i].SlideBBox = r.Candidates[i].SlideBBox.translate(delta)
+	}
+}
+
+// 平移边界框，TargetY为滑块在目标图像中的位置，不随之平移
+func (b SlideBBox) translate(delta image.Point) SlideBBox {
+	b.X1 += delta.X
+	b.X2 += delta.X
+	b.Y1 += delta.Y
+	b.Y2 += delta.Y
+	b.SubX += float64(delta.X)
+	b.SubY += float64(delta.Y)
+	return b
+}
@@ -0,0 +1,296 @@
+package ddddgocr
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"math"
+	"sort"
+	"time"
+)
+
+// ClickPoint 一个图标在背景中的位置
+type ClickPoint struct {
+	X, Y           int     // 点击位置，即图标中心
+	X1, Y1, X2, Y2 int     // 图标在背景中的边界框
+	Angle          float64 // 匹配时图标顺时针旋转的角度（度）
+	Score          float64 // 带掩码的边缘标准化相关系数
+}
+
+// ClickResult 点选验证码的匹配结果
+type ClickResult struct {
+	Points  []ClickPoint  // 按提示条中图标的顺序排列的点击位置
+	Engine  string        // 执行匹配的引擎
+	Elapsed time.Duration // 匹配耗时
+}
+
+// ClickMatch 点选验证码匹配，prompt为按顺序排列图标的提示条，background为包含这些图标的背景
+func ClickMatch(promptImageData, backgroundImageData []byte, opts ...Option) (*ClickResult, error) {
+	return ClickMatchContext(context.Background(), promptImageData, backgroundImageData, opts...)
+}
+
+// ClickMatchContext 可取消的点选验证码匹配
+func ClickMatchContext(ctx context.Context, promptImageData, backgroundImageData []byte, opts ...Option) (*ClickResult, error) {
+	start := time.Now()
+
+	// 解码图像，提示条为目标图像
+	promptImg, backgroundImg, err := decodeImages(promptImageData, backgroundImageData)
+	if err != nil {
+		return nil, err
+	}
+
+	return clickMatch(ctx, start, promptImg, backgroundImg, NewOptions(opts...))
+}
+
+// ClickMatchImage 对已解码图像进行点选验证码匹配
+func ClickMatchImage(ctx context.Context, promptImg, backgroundImg image.Image, opts ...Option) (*ClickResult, error) {
+	return clickMatch(ctx, time.Now(), originImage(promptImg), originImage(backgroundImg), NewOptions(opts...))
+}
+
+// 提示条中的一个图标
+type promptIcon struct {
+	gray *image.Gray
+	mask *image.Alpha
+}
+
+// 图标在某个位置的匹配
+type iconMatch struct {
+	icon  int
+	point ClickPoint
+}
+
+// 图标掩码向外扩展的像素数
+const clickMaskPadding = 2
+
+// 点选验证码匹配流程，start为计时起点：
+// 拆分提示条中的图标后，在一组旋转角度下以边缘图做带掩码的模板匹配，图标颜色变化不影响边缘；
+// 各图标的候选位置按得分从高到低分配，互相重叠的位置只分配给一个图标
+func clickMatch(ctx context.Context, start time.Time, promptImg, backgroundImg image.Image, o *Options) (*ClickResult, error) {
+	icons, err := splitPrompt(ctx, toRGBA(promptImg), o.ClickForeground)
+	if err != nil {
+		return nil, err
+	}
+	if len(icons) == 0 {
+		return nil, ErrNoMatch
+	}
+
+	// 背景边缘，模糊后容忍轻微的形变与旋转误差
+	backgroundGray, err := toGrayScale(ctx, backgroundImg, o.workerCount())
+	if err != nil {
+		return nil, err
+	}
+	backgroundFeature, err := edgeFeature(ctx, backgroundGray, o)
+	if err != nil {
+		return nil, err
+	}
+
+	// 旋转角度，0°在前
+	angles := []float64{0}
+	if o.ClickAngleStep > 0 {
+		for a := o.ClickAngleStep; a <= o.ClickMaxAngle+1e-9; a += o.ClickAngleStep {
+			angles = append(angles, a, -a)
+		}
+	}
+
+	// 每个图标保留若干个候选位置，供冲突时改用
+	const perIcon = 5
+	matches := make([]iconMatch, 0, len(icons)*perIcon)
+	for i, icon := range icons {
+		// 先求边缘再旋转，避免旋转后画布边界产生的边缘
+		iconFeature, err := edgeFeature(ctx, icon.gray, o)
+		if err != nil {
+			return nil, err
+		}
+
+		best := make([]ClickPoint, 0)
+		for _, angle := range angles {
+			feature, mask := rotatePiece(iconFeature, icon.mask, angle)
+			width, height := feature.Bounds().Dx(), feature.Bounds().Dy()
+
+			matchResult, err := matchTemplate(ctx, backgroundFeature, feature, mask, o)
+			if err != nil {
+				return nil, err
+			}
+			if matchResult == nil {
+				continue
+			}
+
+			for _, p := range findPeaks(matchResult, perIcon, width, height) {
+				best = append(best, ClickPoint{
+					X: p.x + width/2, Y: p.y + height/2,
+					X1: p.x, Y1: p.y, X2: p.x + width, Y2: p.y + height,
+					Angle: angle,
+					Score: p.score,
+				})
+			}
+		}
+
+		// 同一位置在不同角度下只保留得分最高者
+		sort.SliceStable(best, func(a, b int) bool {
+			return best[a].Score > best[b].Score
+		})
+		kept := 0
+		for _, point := range best {
+			if kept >= perIcon {
+				break
+			}
+			duplicate := false
+			for _, m := range matches[len(matches)-kept:] {
+				if clickOverlap(m.point, point) {
+					duplicate = true
+					break
+				}
+			}
+			if !duplicate {
+				matches = append(matches, iconMatch{icon: i, point: point})
+				kept++
+			}
+		}
+	}
+
+	// 按得分从高到低分配，已分配的图标与重叠的位置不再使用
+	sort.SliceStable(matches, func(a, b int) bool {
+		return matches[a].point.Score > matches[b].point.Score
+	})
+	points := make([]ClickPoint, len(icons))
+	assigned := make([]bool, len(icons))
+	taken := make([]ClickPoint, 0, len(icons))
+	for _, m := range matches {
+		if assigned[m.icon] {
+			continue
+		}
+		conflict := false
+		for _, t := range taken {
+			if clickOverlap(t, m.point) {
+				conflict = true
+				break
+			}
+		}
+		if conflict {
+			continue
+		}
+		points[m.icon] = m.point
+		assigned[m.icon] = true
+		taken = append(taken, m.point)
+	}
+	for _, ok := range assigned {
+		if !ok {
+			return nil, ErrNoMatch
+		}
+	}
+
+	return &ClickResult{Points: points, Engine: EngineName, Elapsed: time.Since(start)}, nil
+}
+
+// 模糊后的Canny边缘图；高斯模糊不处理最外圈像素，Canny会在图像边界内侧检出虚假边缘，需清除
+func edgeFeature(ctx context.Context, gray *image.Gray, o *Options) (*image.Gray, error) {
+	edges, err := cannyEdgeDetection(ctx, cropGray(gray, gray.Bounds()), o.CannyLow.Low, o.CannyLow.High, o.workerCount())
+	if err != nil {
+		return nil, err
+	}
+	width, height := edges.Bounds().Dx(), edges.Bounds().Dy()
+	for y := range height {
+		for x := range width {
+			if x < 2 || y < 2 || x >= width-2 || y >= height-2 {
+				edges.Pix[y*edges.Stride+x] = 0
+			}
+		}
+	}
+	return gaussianBlur(ctx, edges, o.workerCount())
+}
+
+// 两个位置的中心是否落在对方的边界框内
+func clickOverlap(a, b ClickPoint) bool {
+	return (a.X >= b.X1 && a.X < b.X2 && a.Y >= b.Y1 && a.Y < b.Y2) ||
+		(b.X >= a.X1 && b.X < a.X2 && b.Y >= a.Y1 && b.Y < a.Y2)
+}
+
+// 拆分提示条：带透明通道时不透明像素为前景，否则与四角背景色的RGB平均差超过threshold的像素为前景；
+// 前景列按间隔分段，每段裁剪到前景的上下边界即为一个图标，宽或高小于4像素的段视为噪点；
+// 掩码为膨胀后的前景
+func splitPrompt(ctx context.Context, img *image.RGBA, threshold int) ([]promptIcon, error) {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	// 四角颜色的平均值作为背景色
+	corners := []color.RGBA{
+		img.RGBAAt(bounds.Min.X, bounds.Min.Y),
+		img.RGBAAt(bounds.Max.X-1, bounds.Min.Y),
+		img.RGBAAt(bounds.Min.X, bounds.Max.Y-1),
+		img.RGBAAt(bounds.Max.X-1, bounds.Max.Y-1),
+	}
+	var bgR, bgG, bgB int
+	for _, c := range corners {
+		bgR += int(c.R)
+		bgG += int(c.G)
+		bgB += int(c.B)
+	}
+	bgR, bgG, bgB = bgR/4, bgG/4, bgB/4
+	transparent := alphaMask(img) != nil
+
+	foreground := image.NewAlpha(image.Rect(0, 0, width, height))
+	columns := make([]int, width)
+	for y := range height {
+		for x := range width {
+			c := img.RGBAAt(bounds.Min.X+x, bounds.Min.Y+y)
+			var fg bool
+			if transparent {
+				fg = c.A != 0
+			} else {
+				diff := math.Abs(float64(int(c.R)-bgR)) + math.Abs(float64(int(c.G)-bgG)) + math.Abs(float64(int(c.B)-bgB))
+				fg = diff/3 > float64(threshold)
+			}
+			if fg {
+				foreground.Pix[y*foreground.Stride+x] = 255
+				columns[x]++
+			}
+		}
+	}
+
+	gray := rgbaToGrayScale(img)
+	icons := make([]promptIcon, 0)
+	for x := 0; x < width; {
+		if columns[x] == 0 {
+			x++
+			continue
+		}
+		left := x
+		for x < width && columns[x] > 0 {
+			x++
+		}
+
+		// 该段前景的上下边界
+		top, bottom := height, 0
+		for y := range height {
+			for cx := left; cx < x; cx++ {
+				if foreground.Pix[y*foreground.Stride+cx] != 0 {
+					top = min(top, y)
+					bottom = max(bottom, y+1)
+					break
+				}
+			}
+		}
+		if x-left < 4 || bottom-top < 4 {
+			continue
+		}
+
+		// 向外扩展并膨胀掩码，使图标轮廓上的边缘落在掩码内
+		r := image.Rect(left, top, x, bottom).Inset(-clickMaskPadding).Intersect(image.Rect(0, 0, width, height))
+		region := image.NewGray(image.Rect(0, 0, r.Dx(), r.Dy()))
+		for y := range r.Dy() {
+			copy(region.Pix[y*region.Stride:y*region.Stride+r.Dx()], foreground.Pix[(r.Min.Y+y)*foreground.Stride+r.Min.X:])
+		}
+		for _, horizontal := range []bool{true, false} {
+			var err error
+			if region, err = morphPass(ctx, region, 2*clickMaskPadding+1, false, horizontal, 1); err != nil {
+				return nil, err
+			}
+		}
+		mask := image.NewAlpha(region.Bounds())
+		copy(mask.Pix, region.Pix)
+
+		icons = append(icons, promptIcon{gray: cropGray(gray, r.Add(bounds.Min)), mask: mask})
+	}
+
+	return icons, nil
+}
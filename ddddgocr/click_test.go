@@ -0,0 +1,121 @@
+package ddddgocr
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+const clickIconSize = 24
+
+// 图标形状，(u, v)相对24×24图标框的中心：0为十字，1为圆环，2为尖端朝上的三角形
+func inClickIcon(kind int, u, v float64) bool {
+	switch kind {
+	case 0:
+		return (math.Abs(u) <= 3 && math.Abs(v) <= 11) || (math.Abs(v) <= 3 && math.Abs(u) <= 11)
+	case 1:
+		r := math.Hypot(u, v)
+		return r >= 6 && r <= 11
+	default:
+		return v >= -10 && v <= 10 && math.Abs(u) <= (v+10)/2
+	}
+}
+
+// 以center为中心绘制顺时针旋转degrees度的图标
+func drawClickIcon(img *image.RGBA, kind int, center image.Point, degrees float64, c color.RGBA) {
+	sin, cos := math.Sincos(degrees * math.Pi / 180)
+	for y := center.Y - clickIconSize; y < center.Y+clickIconSize; y++ {
+		for x := center.X - clickIconSize; x < center.X+clickIconSize; x++ {
+			dx, dy := float64(x)+0.5-float64(center.X), float64(y)+0.5-float64(center.Y)
+			if inClickIcon(kind, cos*dx+sin*dy, -sin*dx+cos*dy) {
+				img.SetRGBA(x, y, c)
+			}
+		}
+	}
+}
+
+// 白底提示条，图标按kinds的顺序从左到右排列，间隔16像素
+func clickPrompt(kinds ...int) *image.RGBA {
+	prompt := image.NewRGBA(image.Rect(0, 0, 16+len(kinds)*(clickIconSize+16), clickIconSize+16))
+	for i := range prompt.Pix {
+		prompt.Pix[i] = 255
+	}
+	for i, kind := range kinds {
+		drawClickIcon(prompt, kind, image.Pt(16+i*(clickIconSize+16)+clickIconSize/2, prompt.Bounds().Dy()/2), 0, color.RGBA{R: 40, G: 40, B: 40, A: 255})
+	}
+	return prompt
+}
+
+// 平滑彩色纹理背景
+func clickBackground() *image.RGBA {
+	background := image.NewRGBA(image.Rect(0, 0, 300, 160))
+	for y := range 160 {
+		for x := range 300 {
+			fx, fy := float64(x), float64(y)
+			background.SetRGBA(x, y, color.RGBA{
+				R: uint8(120 + 25*math.Sin(fx/23+fy/31)),
+				G: uint8(120 + 25*math.Cos(fy/19-fx/37)),
+				B: uint8(120 + 25*math.Sin((fx+2*fy)/29)),
+				A: 255,
+			})
+		}
+	}
+	return background
+}
+
+func TestClickMatch(t *testing.T) {
+	dark := color.RGBA{R: 40, G: 40, B: 40, A: 255}
+	background := clickBackground()
+	// 十字原样粘贴，圆环换色，三角形顺时针旋转8°；背景中的顺序与提示条不同
+	want := []image.Point{{230, 110}, {60, 50}, {140, 100}}
+	drawClickIcon(background, 0, want[0], 0, dark)
+	drawClickIcon(background, 1, want[1], 0, color.RGBA{R: 230, G: 200, B: 30, A: 255})
+	drawClickIcon(background, 2, want[2], 8, dark)
+
+	result, err := ClickMatchImage(context.Background(), clickPrompt(0, 1, 2), background)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Points) != len(want) {
+		t.Fatalf("got %d points, want %d", len(result.Points), len(want))
+	}
+	for i, p := range result.Points {
+		if abs(p.X-want[i].X) > 2 || abs(p.Y-want[i].Y) > 2 {
+			t.Errorf("point %d = (%d, %d), want (%d, %d)", i, p.X, p.Y, want[i].X, want[i].Y)
+		}
+	}
+	if angle := result.Points[2].Angle; angle <= 0 || angle > 15 {
+		t.Errorf("rotated icon Angle = %v, want about 8", angle)
+	}
+}
+
+// 提示条中有两个相同的图标时，两处位置各分配一次
+func TestClickMatchDuplicate(t *testing.T) {
+	dark := color.RGBA{R: 40, G: 40, B: 40, A: 255}
+	background := clickBackground()
+	drawClickIcon(background, 2, image.Pt(70, 60), 0, dark)
+	drawClickIcon(background, 2, image.Pt(200, 100), 0, dark)
+	drawClickIcon(background, 0, image.Pt(130, 120), 0, dark)
+
+	result, err := ClickMatchImage(context.Background(), clickPrompt(2, 0, 2), background)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, a := range result.Points {
+		for _, b := range result.Points[i+1:] {
+			if clickOverlap(a, b) {
+				t.Errorf("points %+v and %+v overlap", a, b)
+			}
+		}
+	}
+	if p := result.Points[1]; abs(p.X-130) > 2 || abs(p.Y-120) > 2 {
+		t.Errorf("cross = (%d, %d), want (130, 120)", p.X, p.Y)
+	}
+	near := func(p ClickPoint, x, y int) bool { return abs(p.X-x) <= 2 && abs(p.Y-y) <= 2 }
+	a, b := result.Points[0], result.Points[2]
+	if !(near(a, 70, 60) && near(b, 200, 100)) && !(near(a, 200, 100) && near(b, 70, 60)) {
+		t.Errorf("triangles = (%d, %d), (%d, %d), want (70, 60) and (200, 100)", a.X, a.Y, b.X, b.Y)
+	}
+}
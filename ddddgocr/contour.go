@@ -0,0 +1,363 @@
+package ddddgocr
+
+import (
+	"context"
+	"image"
+	"math"
+	"sort"
+	"time"
+)
+
+// Hu矩取对数时的尺度，远小于该值的Hu矩视为零
+const huEpsilon = 1e-9
+
+// 滑块轮廓模板匹配时，取作搜索窗口的峰值数
+const contourAnchors = 3
+
+// ContourSlideMatch 轮廓形状匹配，用滑块透明通道的轮廓与背景边缘中的封闭轮廓比较形状，适合缺口只有淡描边的情况
+func ContourSlideMatch(targetImageData, backgroundImageData []byte, opts ...Option) (*SlideResult, error) {
+	return ContourSlideMatchContext(context.Background(), targetImageData, backgroundImageData, opts...)
+}
+
+// ContourSlideMatchContext 可取消的轮廓形状匹配
+func ContourSlideMatchContext(ctx context.Context, targetImageData, backgroundImageData []byte, opts ...Option) (*SlideResult, error) {
+	start := time.Now()
+
+	// 解码图像
+	targetImg, backgroundImg, err := decodeImages(targetImageData, backgroundImageData)
+	if err != nil {
+		return nil, err
+	}
+
+	return contourSlideMatch(ctx, start, targetImg, backgroundImg, NewOptions(opts...))
+}
+
+// ContourSlideMatchImage 对已解码图像进行轮廓形状匹配
+func ContourSlideMatchImage(ctx context.Context, targetImg, backgroundImg image.Image, opts ...Option) (*SlideResult, error) {
+	return contourSlideMatch(ctx, time.Now(), originImage(targetImg), originImage(backgroundImg), NewOptions(opts...))
+}
+
+// 轮廓形状匹配流程，start为计时起点；滑块没有透明通道时无法取得轮廓，返回ErrNoMatch
+func contourSlideMatch(ctx context.Context, start time.Time, targetImg, backgroundImg image.Image, o *Options) (*SlideResult, error) {
+	// 检查图像尺寸
+	if err := CheckContains(targetImg.Bounds().Size(), backgroundImg.Bounds().Size()); err != nil {
+		return nil, err
+	}
+
+	// 裁剪透明区域，透明通道即滑块形状
+	cropped, startY, startX := cropTransparent(toRGBA(targetImg))
+	shape := alphaMask(cropped)
+	if shape == nil {
+		return nil, ErrNoMatch
+	}
+
+	// 缺口常只有淡描边，使用低阈值边缘
+	backgroundGray, err := toGrayScale(ctx, backgroundImg, o.workerCount())
+	if err != nil {
+		return nil, err
+	}
+	backgroundEdges, err := cannyEdgeDetection(ctx, backgroundGray, o.CannyLow.Low, o.CannyLow.High, o.workerCount())
+	if err != nil {
+		return nil, err
+	}
+	region := o.SearchRegion(backgroundEdges.Bounds(), shape.Bounds().Size(), &image.Point{X: startX, Y: startY})
+	searchEdges := cropGray(backgroundEdges, region)
+
+	// 滑块轮廓的模板匹配峰值作为轮廓搜索窗口
+	anchors, err := outlineAnchors(ctx, searchEdges, shape, max(o.TopK, contourAnchors), o)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := MatchContour(ctx, searchEdges, shape, anchors, startY, o)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, ErrNoMatch
+	}
+	if result.Score < o.ContourMinScore {
+		return nil, &LowQualityError{Score: result.Score, Threshold: o.ContourMinScore, Strategy: StrategyContour}
+	}
+
+	result.Translate(region.Min)
+	return finishResult(result, start), nil
+}
+
+// ShapeOutline 滑块形状shape的轮廓：不透明且4邻域中有透明像素或位于边界的像素为255，其余为0。
+// 与边缘图做模板匹配得到缺口的大致位置，不含滑块图像边界处的边缘
+func ShapeOutline(shape *image.Alpha) *image.Gray {
+	b := shape.Bounds()
+	outline := image.NewGray(image.Rect(0, 0, b.Dx(), b.Dy()))
+	opaque := func(x, y int) bool {
+		return image.Pt(x, y).In(b) && shape.AlphaAt(x, y).A != 0
+	}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if opaque(x, y) && (!opaque(x-1, y) || !opaque(x+1, y) || !opaque(x, y-1) || !opaque(x, y+1)) {
+				outline.Pix[(y-b.Min.Y)*outline.Stride+x-b.Min.X] = 255
+			}
+		}
+	}
+	return outline
+}
+
+// 滑块轮廓与边缘图edges模板匹配的前k个峰值处的候选框，坐标为edges内的坐标
+func outlineAnchors(ctx context.Context, edges *image.Gray, shape *image.Alpha, k int, o *Options) ([]image.Rectangle, error) {
+	outline := ShapeOutline(shape)
+	matchResult, err := matchTemplate(ctx, edges, outline, nil, o)
+	if err != nil || matchResult == nil {
+		return nil, err
+	}
+	width, height := outline.Bounds().Dx(), outline.Bounds().Dy()
+	return peakBoxes(findPeaks(matchResult, k, width, height), width, height), nil
+}
+
+// 填充后的形状：像素数、质心与Hu矩
+type contourShape struct {
+	area   int
+	cx, cy float64
+	hu     [7]float64
+}
+
+// MatchContour 在背景边缘图edges中寻找与滑块形状shape（裁剪后的透明通道）相似的封闭轮廓：
+// 只分析anchors中各候选框（edges内的坐标，通常为模板匹配的峰值）向四周各扩展半个滑块尺寸的窗口，
+// 窗口内边缘经ContourClose膨胀闭合后，取尺寸与滑块相近的边缘连通区域和被边缘包围的区域，填充内部孔洞后
+// 按Hu矩的对数距离与尺寸比例打分，位置由质心对齐得到；没有候选时返回nil。两种引擎完成边缘检测后共用该分析
+func MatchContour(ctx context.Context, edges *image.Gray, shape *image.Alpha, anchors []image.Rectangle, targetY int, o *Options) (*SlideResult, error) {
+	edges = cropGray(edges, edges.Bounds())
+	pieceWidth, pieceHeight := shape.Bounds().Dx(), shape.Bounds().Dy()
+
+	// 滑块形状
+	shapeBounds := shape.Bounds()
+	piece := newContourShape(image.Rect(0, 0, pieceWidth, pieceHeight), func(x, y int) bool {
+		return shape.AlphaAt(shapeBounds.Min.X+x, shapeBounds.Min.Y+y).A != 0
+	})
+	if piece.area == 0 {
+		return nil, nil
+	}
+
+	candidates := make([]SlideCandidate, 0)
+	for _, anchor := range anchors {
+		window := image.Rect(anchor.Min.X-pieceWidth/2, anchor.Min.Y-pieceHeight/2, anchor.Max.X+pieceWidth/2, anchor.Max.Y+pieceHeight/2).
+			Intersect(edges.Bounds())
+		if window.Empty() {
+			continue
+		}
+		found, err := contourCandidates(ctx, cropGray(edges, window), piece, pieceWidth, pieceHeight, o)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range found {
+			c.TargetY = targetY
+			c.X1 += window.Min.X
+			c.X2 += window.Min.X
+			c.Y1 += window.Min.Y
+			c.Y2 += window.Min.Y
+			c.SubX += float64(window.Min.X)
+			c.SubY += float64(window.Min.Y)
+			candidates = append(candidates, c)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	// 轮廓与其包围的区域常得到同一位置，重叠过半时只保留得分较高者
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
+	kept := make([]SlideCandidate, 0, len(candidates))
+	for _, c := range candidates {
+		duplicate := false
+		for _, k := range kept {
+			if boxIoU(c.SlideBBox, k.SlideBBox) > 0.5 {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			kept = append(kept, c)
+		}
+	}
+
+	result := &SlideResult{
+		SlideBBox:  kept[0].SlideBBox,
+		Score:      kept[0].Score,
+		Margin:     kept[0].Score,
+		Strategy:   StrategyContour,
+		Candidates: kept[:min(o.TopK, len(kept))],
+	}
+	if len(kept) > 1 {
+		result.Margin -= kept[1].Score
+	}
+	return result, nil
+}
+
+// 窗口edges（左上角为原点）内与滑块形状piece相似的封闭轮廓，坐标相对窗口左上角
+func contourCandidates(ctx context.Context, edges *image.Gray, piece contourShape, pieceWidth, pieceHeight int, o *Options) ([]SlideCandidate, error) {
+	width, height := edges.Bounds().Dx(), edges.Bounds().Dy()
+
+	// 膨胀闭合边缘上的小缺口
+	if o.ContourClose > 1 {
+		for _, horizontal := range []bool{true, false} {
+			var err error
+			if edges, err = morphPass(ctx, edges, o.ContourClose, false, horizontal, o.workerCount()); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// 缺口轮廓本身（8连通）与其包围的区域（4连通），内部纹理会把后者分割，前者不受影响
+	outlines, err := labelRegions(ctx, width, height, true, func(i int) bool {
+		return edges.Pix[i] != 0
+	})
+	if err != nil {
+		return nil, err
+	}
+	enclosed, err := labelRegions(ctx, width, height, false, func(i int) bool {
+		return edges.Pix[i] == 0
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]SlideCandidate, 0)
+	for _, region := range append(outlines, enclosed...) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		// 尺寸与滑块相差过大或接触窗口边界的区域不是缺口
+		b := region.bounds
+		if b.Min.X == 0 || b.Min.Y == 0 || b.Max.X == width || b.Max.Y == height {
+			continue
+		}
+		if float64(b.Dx()) < 0.6*float64(pieceWidth) || float64(b.Dx()) > 1.4*float64(pieceWidth) ||
+			float64(b.Dy()) < 0.6*float64(pieceHeight) || float64(b.Dy()) > 1.4*float64(pieceHeight) {
+			continue
+		}
+
+		filled := newContourShape(b, func(x, y int) bool {
+			return region.labels[(b.Min.Y+y)*width+b.Min.X+x] == region.id
+		})
+		score := contourScore(piece, filled)
+		x := filled.cx + float64(b.Min.X) - piece.cx
+		y := filled.cy + float64(b.Min.Y) - piece.cy
+		x1, y1 := int(math.Round(x)), int(math.Round(y))
+		candidates = append(candidates, SlideCandidate{
+			SlideBBox: SlideBBox{
+				X1:   x1,
+				Y1:   y1,
+				X2:   x1 + pieceWidth,
+				Y2:   y1 + pieceHeight,
+				SubX: x,
+				SubY: y,
+				Area: filled.area,
+			},
+			Score:    score,
+			Strategy: StrategyContour,
+		})
+	}
+	return candidates, nil
+}
+
+// 区域bounds内in为真的像素填充孔洞后的形状：从边界出发4连通可达的非区域像素为外部，其余为形状，
+// 坐标相对bounds左上角
+func newContourShape(bounds image.Rectangle, in func(x, y int) bool) contourShape {
+	w, h := bounds.Dx(), bounds.Dy()
+	outside := make([]bool, w*h)
+	queue := make([]int, 0)
+	visit := func(x, y int) {
+		if i := y*w + x; !outside[i] && !in(x, y) {
+			outside[i] = true
+			queue = append(queue, i)
+		}
+	}
+	for x := range w {
+		visit(x, 0)
+		visit(x, h-1)
+	}
+	for y := range h {
+		visit(0, y)
+		visit(w-1, y)
+	}
+	for len(queue) > 0 {
+		i := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+		x, y := i%w, i/w
+		if x > 0 {
+			visit(x-1, y)
+		}
+		if x < w-1 {
+			visit(x+1, y)
+		}
+		if y > 0 {
+			visit(x, y-1)
+		}
+		if y < h-1 {
+			visit(x, y+1)
+		}
+	}
+
+	// 质心
+	var s contourShape
+	var sumX, sumY float64
+	for i, out := range outside {
+		if !out {
+			s.area++
+			sumX += float64(i%w) + 0.5
+			sumY += float64(i/w) + 0.5
+		}
+	}
+	if s.area == 0 {
+		return s
+	}
+	n := float64(s.area)
+	s.cx, s.cy = sumX/n, sumY/n
+
+	// 中心矩
+	var mu20, mu02, mu11, mu30, mu03, mu21, mu12 float64
+	for i, out := range outside {
+		if out {
+			continue
+		}
+		dx, dy := float64(i%w)+0.5-s.cx, float64(i/w)+0.5-s.cy
+		mu20 += dx * dx
+		mu02 += dy * dy
+		mu11 += dx * dy
+		mu30 += dx * dx * dx
+		mu03 += dy * dy * dy
+		mu21 += dx * dx * dy
+		mu12 += dx * dy * dy
+	}
+
+	// 归一化中心矩与Hu矩
+	n2, n3 := math.Pow(n, 2), math.Pow(n, 2.5)
+	e20, e02, e11 := mu20/n2, mu02/n2, mu11/n2
+	e30, e03, e21, e12 := mu30/n3, mu03/n3, mu21/n3, mu12/n3
+	a, b := e30+e12, e21+e03
+	s.hu = [7]float64{
+		e20 + e02,
+		(e20-e02)*(e20-e02) + 4*e11*e11,
+		(e30-3*e12)*(e30-3*e12) + (3*e21-e03)*(3*e21-e03),
+		a*a + b*b,
+		(e30-3*e12)*a*(a*a-3*b*b) + (3*e21-e03)*b*(3*a*a-b*b),
+		(e20-e02)*(a*a-b*b) + 4*e11*a*b,
+		(3*e21-e03)*a*(a*a-3*b*b) - (e30-3*e12)*b*(3*a*a-b*b),
+	}
+	return s
+}
+
+// 形状得分：Hu矩按sign·log10(1+|h|/ε)取对数后的距离d换算为1/(1+d)，乘以两者边长的比例（面积比的平方根）；
+// 与sign·log10|h|相比在零附近连续，接近零的高阶矩改变符号时不会产生很大的距离
+func contourScore(a, b contourShape) float64 {
+	var distance float64
+	for i := range a.hu {
+		ma := math.Copysign(math.Log10(1+math.Abs(a.hu[i])/huEpsilon), a.hu[i])
+		mb := math.Copysign(math.Log10(1+math.Abs(b.hu[i])/huEpsilon), b.hu[i])
+		distance += math.Abs(ma - mb)
+	}
+	size := math.Sqrt(float64(min(a.area, b.area)) / float64(max(a.area, b.area)))
+	return size / (1 + distance)
+}
@@ -0,0 +1,97 @@
+package ddddgocr
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"testing"
+)
+
+// 圆形滑块与只有描边的缺口，背景中另有一个尺寸相同的方形描边干扰
+func TestContourSlideMatch(t *testing.T) {
+	const radius = 18
+	inCircle := func(x, y, cx, cy, r int) bool {
+		return (x-cx)*(x-cx)+(y-cy)*(y-cy) <= r*r
+	}
+
+	target := image.NewRGBA(image.Rect(0, 0, 60, 80))
+	for y := range 80 {
+		for x := range 60 {
+			if inCircle(x, y, 30, 40, radius) {
+				target.SetRGBA(x, y, color.RGBA{R: 200, G: 200, B: 200, A: 255})
+			}
+		}
+	}
+
+	background := image.NewRGBA(image.Rect(0, 0, 300, 80))
+	for y := range 80 {
+		for x := range 300 {
+			v := uint8(90)
+			d := (x-200)*(x-200) + (y-40)*(y-40)
+			if d <= radius*radius && d > (radius-2)*(radius-2) {
+				v = 160
+			}
+			if x >= 60 && x < 97 && y >= 22 && y < 59 && (x < 62 || x >= 95 || y < 24 || y >= 57) {
+				v = 160
+			}
+			background.SetRGBA(x, y, color.RGBA{R: v, G: v, B: v, A: 255})
+		}
+	}
+
+	result, err := ContourSlideMatchImage(context.Background(), target, background)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.X1 < 200-radius-3 || result.X1 > 200-radius+3 {
+		t.Errorf("X1 = %d, want about %d", result.X1, 200-radius)
+	}
+	if result.Strategy != StrategyContour {
+		t.Errorf("Strategy = %v, want %v", result.Strategy, StrategyContour)
+	}
+}
+
+// 只分析候选框附近的窗口，窗口外相同形状的轮廓不会成为候选
+func TestMatchContourWindow(t *testing.T) {
+	const radius = 15
+	ring := func(x, y, cx, cy int) bool {
+		d := (x-cx)*(x-cx) + (y-cy)*(y-cy)
+		return d <= radius*radius && d > (radius-1)*(radius-1)
+	}
+	edges := image.NewGray(image.Rect(0, 0, 240, 60))
+	for y := range 60 {
+		for x := range 240 {
+			if ring(x, y, 50, 30) || ring(x, y, 180, 30) {
+				edges.Pix[y*edges.Stride+x] = 255
+			}
+		}
+	}
+	shape := image.NewAlpha(image.Rect(0, 0, 2*radius+1, 2*radius+1))
+	for y := range 2*radius + 1 {
+		for x := range 2*radius + 1 {
+			if (x-radius)*(x-radius)+(y-radius)*(y-radius) <= radius*radius {
+				shape.Pix[y*shape.Stride+x] = 255
+			}
+		}
+	}
+
+	for _, cx := range []int{50, 180} {
+		anchor := image.Rect(cx-radius+4, 30-radius-3, cx+radius+5, 30+radius-2)
+		result, err := MatchContour(context.Background(), edges, shape, []image.Rectangle{anchor}, 0, NewOptions(WithTopK(5)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result == nil {
+			t.Fatalf("anchor %v: no result", anchor)
+		}
+		for _, c := range result.Candidates {
+			if c.X1 < cx-radius-2 || c.X1 > cx-radius+2 {
+				t.Errorf("anchor %v: candidate X1 = %d, want about %d", anchor, c.X1, cx-radius)
+			}
+		}
+	}
+
+	result, err := MatchContour(context.Background(), edges, shape, nil, 0, NewOptions())
+	if err != nil || result != nil {
+		t.Errorf("no anchors: got %v, %v, want nil", result, err)
+	}
+}
@@ -0,0 +1,198 @@
+package ddddgocr
+
+import (
+	"context"
+	"image"
+	"math"
+	"sort"
+)
+
+// 缺口特征的权重：模板相关系数、轮廓完整度、形状吻合度、明暗对比、位置合理性
+var decoyWeights = [5]float64{0.25, 0.2, 0.2, 0.15, 0.2}
+
+// 明暗对比的环带宽度（像素）
+const decoyRing = 3
+
+// RankGapCandidates 综合缺口特征对模板匹配的候选位置重新排序，排除与滑块形状不符的干扰缺口：
+// 模板相关系数、滑块轮廓上存在背景边缘的比例（完整度）、框内背景边缘落在轮廓附近的比例（吻合度）、
+// 轮廓内外的明暗对比，以及piece不为nil时Y1与滑块在目标图像中位置的接近程度。
+// result的候选已在背景坐标下，shape为产生候选的滑块形状，即按result的倍数与角度变换并裁剪后的透明通道，
+// piece为该裁剪左上角在目标图像中的位置；gray、edges为背景灰度图与边缘图；
+// 重排后Score为综合得分，Margin为与次佳候选之差，候选保留TopK个
+func RankGapCandidates(ctx context.Context, result *SlideResult, gray, edges *image.Gray, shape *image.Alpha, piece *image.Point, o *Options) error {
+	if len(result.Candidates) < 2 || shape == nil {
+		return nil
+	}
+	gray = cropGray(gray, gray.Bounds())
+	edges = cropGray(edges, edges.Bounds())
+
+	profile, err := newGapProfile(ctx, shape, o.workerCount())
+	if err != nil {
+		return err
+	}
+
+	candidates := make([]SlideCandidate, len(result.Candidates))
+	copy(candidates, result.Candidates)
+	for i := range candidates {
+		c := &candidates[i]
+		cues := profile.cues(gray, edges, c.X1, c.Y1)
+		cues[0] = math.Max(0, math.Min(1, c.Score))
+
+		// 位置合理性：Y1偏离滑块位置四分之一个滑块高度时减半
+		weights := decoyWeights
+		if piece != nil {
+			dy := float64(c.Y1-piece.Y) / math.Max(1, float64(profile.height)/4)
+			cues[4] = 1 / (1 + dy*dy)
+		} else {
+			weights[4] = 0
+		}
+
+		var score, total float64
+		for k, w := range weights {
+			score += w * cues[k]
+			total += w
+		}
+		c.Score = score / total
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
+	result.SlideBBox = candidates[0].SlideBBox
+	result.Score = candidates[0].Score
+	result.Margin = candidates[0].Score - candidates[1].Score
+	result.Candidates = candidates[:min(o.TopK, len(candidates))]
+	return nil
+}
+
+// 缩放后滑块形状的轮廓带与外侧环带，坐标相对画布左上角，画布向四周扩展decoyRing像素
+type gapProfile struct {
+	width, height int
+	inside        []int // 形状内部且不在轮廓带上的像素
+	boundary      []int // 形状的边界像素
+	band          []bool
+	ring          []int // 形状外decoyRing像素内的像素
+}
+
+func newGapProfile(ctx context.Context, shape *image.Alpha, workers int) (*gapProfile, error) {
+	bounds := shape.Bounds()
+	p := &gapProfile{width: bounds.Dx(), height: bounds.Dy()}
+	w, h := p.width+2*decoyRing, p.height+2*decoyRing
+
+	in := func(x, y int) bool {
+		x, y = x-decoyRing, y-decoyRing
+		return x >= 0 && y >= 0 && x < p.width && y < p.height && shape.AlphaAt(bounds.Min.X+x, bounds.Min.Y+y).A != 0
+	}
+
+	// 边界像素：4邻域中存在形状外的像素
+	border := image.NewGray(image.Rect(0, 0, w, h))
+	filled := image.NewGray(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			if !in(x, y) {
+				continue
+			}
+			filled.Pix[y*w+x] = 255
+			if !in(x-1, y) || !in(x+1, y) || !in(x, y-1) || !in(x, y+1) {
+				border.Pix[y*w+x] = 255
+				p.boundary = append(p.boundary, y*w+x)
+			}
+		}
+	}
+
+	// 轮廓带为边界膨胀1像素，环带为形状膨胀decoyRing像素后去掉形状
+	band, ring := border, filled
+	for _, horizontal := range []bool{true, false} {
+		var err error
+		if band, err = morphPass(ctx, band, 3, false, horizontal, workers); err != nil {
+			return nil, err
+		}
+		if ring, err = morphPass(ctx, ring, 2*decoyRing+1, false, horizontal, workers); err != nil {
+			return nil, err
+		}
+	}
+	p.band = make([]bool, w*h)
+	for i := range p.band {
+		p.band[i] = band.Pix[i] != 0
+		switch {
+		case filled.Pix[i] != 0 && !p.band[i]:
+			p.inside = append(p.inside, i)
+		case filled.Pix[i] == 0 && ring.Pix[i] != 0:
+			p.ring = append(p.ring, i)
+		}
+	}
+	return p, nil
+}
+
+// 滑块左上角位于(x1, y1)时的各项特征，下标与decoyWeights一致，模板相关系数与位置合理性由调用方填入；
+// 超出背景的像素不参与统计
+func (p *gapProfile) cues(gray, edges *image.Gray, x1, y1 int) [5]float64 {
+	var cues [5]float64
+	w := p.width + 2*decoyRing
+	bgWidth, bgHeight := gray.Bounds().Dx(), gray.Bounds().Dy()
+	at := func(i int) (int, bool) {
+		x, y := x1+i%w-decoyRing, y1+i/w-decoyRing
+		if x < 0 || y < 0 || x >= bgWidth || y >= bgHeight {
+			return 0, false
+		}
+		return y*bgWidth + x, true
+	}
+
+	// 完整度：边界像素的3x3邻域内存在背景边缘
+	outlined, boundary := 0, 0
+	for _, i := range p.boundary {
+		j, ok := at(i)
+		if !ok {
+			continue
+		}
+		boundary++
+		x, y := j%bgWidth, j/bgWidth
+	neighbors:
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				if xx, yy := x+dx, y+dy; xx >= 0 && yy >= 0 && xx < bgWidth && yy < bgHeight && edges.Pix[yy*bgWidth+xx] != 0 {
+					outlined++
+					break neighbors
+				}
+			}
+		}
+	}
+	if boundary > 0 {
+		cues[1] = float64(outlined) / float64(boundary)
+	}
+
+	// 吻合度：画布内的背景边缘落在轮廓带上的比例
+	inBand, total := 0, 0
+	for i, banded := range p.band {
+		j, ok := at(i)
+		if !ok || edges.Pix[j] == 0 {
+			continue
+		}
+		total++
+		if banded {
+			inBand++
+		}
+	}
+	if total > 0 {
+		cues[2] = float64(inBand) / float64(total)
+	}
+
+	// 明暗对比：轮廓内与外侧环带的平均亮度相对差，差30%即为满分
+	mean := func(pixels []int) (float64, bool) {
+		var sum float64
+		n := 0
+		for _, i := range pixels {
+			if j, ok := at(i); ok {
+				sum += float64(gray.Pix[j])
+				n++
+			}
+		}
+		return sum / float64(max(n, 1)), n > 0
+	}
+	inside, ok1 := mean(p.inside)
+	ring, ok2 := mean(p.ring)
+	if ok1 && ok2 {
+		cues[3] = math.Min(1, math.Abs(ring-inside)/math.Max(ring, 1)/0.3)
+	}
+	return cues
+}
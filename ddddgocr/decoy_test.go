@@ -0,0 +1,149 @@
+package ddddgocr
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+)
+
+const decoySize, decoyGapX, decoyX, decoyGapY = 40, 190, 70, 30
+
+// 背景中有真实缺口与宽高相同的方形干扰缺口，两处纹理相同，只有形状不同；滑块从真实缺口处切出，
+// 目标图像保留透明边距，滑块位置已知
+func decoyScene() (target, background *image.RGBA) {
+	// 滑块为右侧带半圆凸起的方块
+	inPiece := func(x, y int) bool {
+		if x >= 0 && x < decoySize && y >= 0 && y < decoySize {
+			return true
+		}
+		dx, dy := x-decoySize, y-decoySize/2
+		return dx >= 0 && dx*dx+dy*dy <= 8*8
+	}
+	inDecoy := func(x, y int) bool {
+		return x >= 0 && x < decoySize+8 && y >= 0 && y < decoySize
+	}
+
+	// 6×6像素的随机块纹理
+	rng := rand.New(rand.NewSource(1))
+	blocks := make([]uint8, 60*20)
+	for i := range blocks {
+		blocks[i] = uint8(80 + rng.Intn(140))
+	}
+	texture := func(x, y int) uint8 {
+		if x >= decoyX && x < decoyX+decoySize+8 {
+			x += decoyGapX - decoyX
+		}
+		return blocks[y/6*60+x/6]
+	}
+
+	background = image.NewRGBA(image.Rect(0, 0, 320, 110))
+	for y := range 110 {
+		for x := range 320 {
+			v := texture(x, y)
+			if inPiece(x-decoyGapX, y-decoyGapY) || inDecoy(x-decoyX, y-decoyGapY) {
+				v -= 40
+			}
+			background.SetRGBA(x, y, color.RGBA{R: v, G: v, B: v, A: 255})
+		}
+	}
+
+	target = image.NewRGBA(image.Rect(0, 0, decoySize+10, 110))
+	for y := range 110 {
+		for x := range decoySize + 10 {
+			if inPiece(x, y-decoyGapY) {
+				v := texture(x+decoyGapX, y)
+				target.SetRGBA(x, y, color.RGBA{R: v, G: v, B: v, A: 255})
+			}
+		}
+	}
+	return target, background
+}
+
+// 干扰缺口的相关系数更高时，综合缺口特征后仍选中真实缺口
+func TestRankGapCandidates(t *testing.T) {
+	target, background := decoyScene()
+	o := NewOptions(WithTopK(2))
+	cropped, startY, startX := cropTransparent(target)
+	shape := alphaMask(cropped)
+	gray, err := toGrayScale(context.Background(), background, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edges, err := cannyEdgeDetection(context.Background(), gray, o.CannyLow.Low, o.CannyLow.High, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	box := func(x int) SlideBBox {
+		return SlideBBox{X1: x, Y1: decoyGapY, X2: x + cropped.Bounds().Dx(), Y2: decoyGapY + cropped.Bounds().Dy()}
+	}
+	result := &SlideResult{
+		SlideBBox: box(decoyX),
+		Score:     0.8,
+		Candidates: []SlideCandidate{
+			{SlideBBox: box(decoyX), Score: 0.8, Strategy: StrategyEdge},
+			{SlideBBox: box(decoyGapX), Score: 0.7, Strategy: StrategyEdge},
+		},
+	}
+	err = RankGapCandidates(context.Background(), result, gray, edges, shape, &image.Point{X: startX, Y: startY}, o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.X1 != decoyGapX {
+		t.Errorf("X1 = %d, want %d", result.X1, decoyGapX)
+	}
+	if result.Margin <= 0 || result.Candidates[1].X1 != decoyX {
+		t.Errorf("Margin = %v, candidates = %+v", result.Margin, result.Candidates)
+	}
+}
+
+func TestSlideMatchDecoy(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{"default", nil},
+		{"more candidates", []Option{WithDecoyCandidates(10)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, background := decoyScene()
+			result, err := SlideMatchImage(context.Background(), target, background, append(tt.opts, WithTopK(3))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.X1 != decoyGapX {
+				t.Errorf("X1 = %d, want %d", result.X1, decoyGapX)
+			}
+			if result.Score < 0 || result.Score > 1 {
+				t.Errorf("Score = %v, want within [0, 1]", result.Score)
+			}
+			if len(result.Candidates) != 3 || result.Candidates[1].X1 != decoyX {
+				t.Errorf("candidates = %+v, want 3 with the decoy second", result.Candidates)
+			}
+		})
+	}
+}
+
+// 设置为0或1时关闭重新排序，Score保持为相关系数
+func TestSlideMatchDecoyOptOut(t *testing.T) {
+	target, background := decoyScene()
+	ranked, err := SlideMatchImage(context.Background(), target, background)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range []int{0, 1} {
+		result, err := SlideMatchImage(context.Background(), target, background, WithDecoyCandidates(n))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.X1 != decoyGapX {
+			t.Errorf("n = %d: X1 = %d, want %d", n, result.X1, decoyGapX)
+		}
+		if result.Score == ranked.Score || result.Score != result.Candidates[0].Score {
+			t.Errorf("n = %d: Score = %v, ranked Score = %v, want the unranked coefficient", n, result.Score, ranked.Score)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package ddddgocr
+
+import (
+	"errors"
+	"fmt"
+	"image"
+)
+
+// Language 错误信息语言
+type Language int
+
+const (
+	Chinese Language = iota // 中文（Error()使用的语言）
+	English                 // 英文
+)
+
+// LocalizedError 可按指定语言输出信息的错误，本包的哨兵错误与类型化错误均实现该接口
+type LocalizedError interface {
+	error
+	Localized(lang Language) string
+}
+
+// Localize 按指定语言输出错误信息：使用错误链中首个实现LocalizedError的错误的信息，
+// 均未实现时使用Error()
+func Localize(err error, lang Language) string {
+	if err == nil {
+		return ""
+	}
+	var localized LocalizedError
+	if errors.As(err, &localized) {
+		return localized.Localized(lang)
+	}
+	return err.Error()
+}
+
+// 按语言选择文本
+func localize(lang Language, zh, en string) string {
+	if lang == English {
+		return en
+	}
+	return zh
+}
+
+// 哨兵错误
+type sentinelError struct {
+	zh, en string
+}
+
+func (e *sentinelError) Error() string {
+	return e.Localized(Chinese)
+}
+
+func (e *sentinelError) Localized(lang Language) string {
+	return localize(lang, e.zh, e.en)
+}
+
+// 可用errors.Is判断的错误类别
+var (
+	ErrLowQuality        error = &sentinelError{"匹配质量过低", "match quality too low"}
+	ErrSizeMismatch      error = &sentinelError{"图片尺寸不符", "image size mismatch"}
+	ErrDecode            error = &sentinelError{"解码图像失败", "failed to decode image"}
+	ErrUnknownMatchType  error = &sentinelError{"匹配类型错误", "unknown match type"}
+	ErrEngineUnavailable error = &sentinelError{"匹配引擎不可用", "match engine unavailable"}
+	ErrTemplateMatch     error = &sentinelError{"模板匹配失败", "template matching failed"}
+	ErrNoMatch           error = &sentinelError{"所有匹配策略都失败了", "all match strategies failed"}
+	ErrEmptyImage        error = &sentinelError{"图像为空", "image is empty"}
+	ErrStripOffset       error = &sentinelError{"分块偏移无效", "invalid strip offset"}
+	ErrInvalidLayout     error = &sentinelError{"分块排列参数无效", "invalid strip layout"}
+)
+
+// ImageRole 图像在匹配中的角色
+type ImageRole string
+
+const (
+	RoleTarget     ImageRole = "target"     // 目标图像（滑块或完整图）
+	RoleBackground ImageRole = "background" // 背景图像
+)
+
+// 角色的本地化名称
+func (r ImageRole) name(lang Language) string {
+	switch r {
+	case RoleTarget:
+		return localize(lang, "目标图像", "target image")
+	case RoleBackground:
+		return localize(lang, "背景图像", "background image")
+	default:
+		return string(r)
+	}
+}
+
+// LowQualityError 最佳得分低于阈值
+type LowQualityError struct {
+	Score     float64  // 最佳得分
+	Threshold float64  // 要求的最低得分
+	Strategy  Strategy // 产生得分的策略
+}
+
+func (e *LowQualityError) Error() string {
+	return e.Localized(Chinese)
+}
+
+func (e *LowQualityError) Localized(lang Language) string {
+	return localize(lang,
+		fmt.Sprintf("匹配质量过低: 得分 %.4f 低于阈值 %.4f", e.Score, e.Threshold),
+		fmt.Sprintf("match quality too low: score %.4f below threshold %.4f", e.Score, e.Threshold),
+	)
+}
+
+func (e *LowQualityError) Is(target error) bool {
+	return target == ErrLowQuality
+}
+
+// SizeMismatchError 目标图像与背景图像尺寸不符合匹配方式的要求
+type SizeMismatchError struct {
+	Target     image.Point // 目标图像尺寸
+	Background image.Point // 背景图像尺寸
+	Equal      bool        // 是否要求尺寸相等，否则要求背景不小于目标
+}
+
+func (e *SizeMismatchError) Error() string {
+	return e.Localized(Chinese)
+}
+
+func (e *SizeMismatchError) Localized(lang Language) string {
+	if e.Equal {
+		return localize(lang,
+			fmt.Sprintf("图片尺寸不相等: 目标图片 %dx%d, 背景图片 %dx%d", e.Target.X, e.Target.Y, e.Background.X, e.Background.Y),
+			fmt.Sprintf("image sizes differ: target %dx%d, background %dx%d", e.Target.X, e.Target.Y, e.Background.X, e.Background.Y),
+		)
+	}
+	return localize(lang,
+		fmt.Sprintf("背景图片尺寸 %dx%d 必须大于等于目标图片尺寸 %dx%d", e.Background.X, e.Background.Y, e.Target.X, e.Target.Y),
+		fmt.Sprintf("background size %dx%d must not be smaller than target size %dx%d", e.Background.X, e.Background.Y, e.Target.X, e.Target.Y),
+	)
+}
+
+func (e *SizeMismatchError) Is(target error) bool {
+	return target == ErrSizeMismatch
+}
+
+// CheckContains 检查背景尺寸不小于目标尺寸
+func CheckContains(target, background image.Point) error {
+	if background.X < target.X || background.Y < target.Y {
+		return &SizeMismatchError{Target: target, Background: background}
+	}
+	return nil
+}
+
+// CheckEqual 检查两图尺寸相等
+func CheckEqual(target, background image.Point) error {
+	if target != background {
+		return &SizeMismatchError{Target: target, Background: background, Equal: true}
+	}
+	return nil
+}
+
+// DecodeError 读取或解码图像失败
+type DecodeError struct {
+	Image ImageRole // 出错的图像
+	Err   error     // 底层错误
+}
+
+func (e *DecodeError) Error() string {
+	return e.Localized(Chinese)
+}
+
+func (e *DecodeError) Localized(lang Language) string {
+	return localize(lang, "解码", "failed to decode ") + e.Image.name(lang) + localize(lang, "失败: ", ": ") + Localize(e.Err, lang)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+func (e *DecodeError) Is(target error) bool {
+	return target == ErrDecode
+}
+
+// UnknownMatchTypeError 引擎不支持的匹配方式
+type UnknownMatchTypeError struct {
+	Type string
+}
+
+func (e *UnknownMatchTypeError) Error() string {
+	return e.Localized(Chinese)
+}
+
+func (e *UnknownMatchTypeError) Localized(lang Language) string {
+	return localize(lang, "匹配类型错误: ", "unknown match type: ") + e.Type
+}
+
+func (e *UnknownMatchTypeError) Is(target error) bool {
+	return target == ErrUnknownMatchType
+}
+
+// EngineUnavailableError 请求的匹配引擎未注册或未编译
+type EngineUnavailableError struct {
+	Engine string
+}
+
+func (e *EngineUnavailableError) Error() string {
+	return e.Localized(Chinese)
+}
+
+func (e *EngineUnavailableError) Localized(lang Language) string {
+	if e.Engine == "opencv" {
+		return localize(lang, "OpenCV 支持未启用，请使用 -tags opencv 编译", "OpenCV support is disabled, build with -tags opencv")
+	}
+	return localize(lang, "未注册的匹配引擎: ", "match engine not registered: ") + e.Engine
+}
+
+func (e *EngineUnavailableError) Is(target error) bool {
+	return target == ErrEngineUnavailable
+}
+
+// StripOffsetError 分块偏移列表无法解析或超出拼图范围
+type StripOffsetError struct {
+	Index int    // 出错的偏移序号，整个列表格式错误时为-1
+	Value string // 出错的原始值
+}
+
+func (e *StripOffsetError) Error() string {
+	return e.Localized(Chinese)
+}
+
+func (e *StripOffsetError) Localized(lang Language) string {
+	if e.Index < 0 {
+		return localize(lang, "分块偏移列表格式错误: ", "invalid strip offset list: ") + e.Value
+	}
+	return localize(lang,
+		fmt.Sprintf("第%d个分块偏移无效: %s", e.Index, e.Value),
+		fmt.Sprintf("invalid strip offset #%d: %s", e.Index, e.Value),
+	)
+}
+
+func (e *StripOffsetError) Is(target error) bool {
+	return target == ErrStripOffset
+}
+
+// InvalidLayoutError 分块排列参数缺失或无效
+type InvalidLayoutError struct {
+	Field string // 出错的字段名，如Width、Height
+	Value int    // 字段的值，由拼图尺寸推算时为推算结果
+}
+
+func (e *InvalidLayoutError) Error() string {
+	return e.Localized(Chinese)
+}
+
+func (e *InvalidLayoutError) Localized(lang Language) string {
+	return localize(lang,
+		fmt.Sprintf("分块排列参数无效: %s 为 %d，需大于0", e.Field, e.Value),
+		fmt.Sprintf("invalid strip layout: %s is %d, must be positive", e.Field, e.Value),
+	)
+}
+
+func (e *InvalidLayoutError) Is(target error) bool {
+	return target == ErrInvalidLayout
+}
@@ -0,0 +1,160 @@
+package ddddgocr
+
+import (
+	"context"
+	"image"
+	"math"
+	"math/bits"
+	"math/cmplx"
+)
+
+// 不小于n的最小2的幂
+func nextPowerOfTwo(n int) int {
+	if n <= 1 {
+		return 1
+	}
+	return 1 << bits.Len(uint(n-1))
+}
+
+// FFT计划，缓存长度为n的旋转因子
+type fftPlan struct {
+	n        int
+	twiddles []complex128
+}
+
+// 创建长度为n（2的幂）的FFT计划，inverse为逆变换（未除以长度）
+func newFFTPlan(n int, inverse bool) *fftPlan {
+	sign := -1.0
+	if inverse {
+		sign = 1.0
+	}
+
+	twiddles := make([]complex128, n/2)
+	for k := range twiddles {
+		twiddles[k] = cmplx.Rect(1, sign*2*math.Pi*float64(k)/float64(n))
+	}
+	return &fftPlan{n: n, twiddles: twiddles}
+}
+
+// 原地迭代基2快速傅里叶变换
+func (p *fftPlan) transform(a []complex128) {
+	n := p.n
+
+	// 位反转置换
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j |= bit
+		if i < j {
+			a[i], a[j] = a[j], a[i]
+		}
+	}
+
+	for size := 2; size <= n; size <<= 1 {
+		half := size >> 1
+		stride := n / size
+		for start := 0; start < n; start += size {
+			for k := range half {
+				u := a[start+k]
+				v := a[start+k+half] * p.twiddles[k*stride]
+				a[start+k] = u + v
+				a[start+k+half] = u - v
+			}
+		}
+	}
+}
+
+// 二维FFT，data按行存储，宽w高h均为2的幂，行与列分别按workers并行变换
+func fft2D(ctx context.Context, data []complex128, w, h int, inverse bool, workers int) error {
+	rowPlan := newFFTPlan(w, inverse)
+	err := parallelRows(ctx, 0, h, workers, func(y int) {
+		rowPlan.transform(data[y*w : (y+1)*w])
+	})
+	if err != nil {
+		return err
+	}
+
+	columnPlan := newFFTPlan(h, inverse)
+	return parallelRows(ctx, 0, w, workers, func(x int) {
+		column := make([]complex128, h)
+		for y := range h {
+			column[y] = data[y*w+x]
+		}
+		columnPlan.transform(column)
+		for y := range h {
+			data[y*w+x] = column[y]
+		}
+	})
+}
+
+// 将srcWidth×srcHeight的数据补零到width×height后做二维FFT
+func forwardSpectrum(ctx context.Context, values []float64, srcWidth, srcHeight, width, height, workers int) ([]complex128, error) {
+	spectrum := make([]complex128, width*height)
+	for y := range srcHeight {
+		for x := range srcWidth {
+			spectrum[y*width+x] = complex(values[y*srcWidth+x], 0)
+		}
+	}
+
+	if err := fft2D(ctx, spectrum, width, height, false, workers); err != nil {
+		return nil, err
+	}
+	return spectrum, nil
+}
+
+// 由背景与模板的频谱求互相关，互相关对应背景频谱乘以模板频谱的共轭，两个频谱均不被修改
+func correlateSpectra(ctx context.Context, bgSpectrum, tplSpectrum []complex128, width, height, resultWidth, resultHeight, workers int) ([][]float64, error) {
+	product := make([]complex128, len(bgSpectrum))
+	for i := range product {
+		product[i] = bgSpectrum[i] * cmplx.Conj(tplSpectrum[i])
+	}
+
+	if err := fft2D(ctx, product, width, height, true, workers); err != nil {
+		return nil, err
+	}
+
+	scale := 1 / float64(width*height)
+	result := make([][]float64, resultHeight)
+	for y := range resultHeight {
+		result[y] = make([]float64, resultWidth)
+		for x := range resultWidth {
+			result[y][x] = real(product[y*width+x]) * scale
+		}
+	}
+
+	return result, nil
+}
+
+// 灰度图的像素值，按行存储
+func grayValues(img *image.Gray) []float64 {
+	bounds := img.Bounds()
+	values := make([]float64, bounds.Dx()*bounds.Dy())
+	for y := range bounds.Dy() {
+		row := img.Pix[img.PixOffset(bounds.Min.X, bounds.Min.Y+y):]
+		for x := range bounds.Dx() {
+			values[y*bounds.Dx()+x] = float64(row[x])
+		}
+	}
+	return values
+}
+
+// 频域互相关，返回corr[y][x] = Σ background(x+i, y+j)·template[j*tplWidth+i]，
+// 补零尺寸不小于背景尺寸，有效区域内不会发生循环卷绕
+func correlateFFT(ctx context.Context, background *image.Gray, template []float64, tplWidth, tplHeight, resultWidth, resultHeight, workers int) ([][]float64, error) {
+	bounds := background.Bounds()
+	width := nextPowerOfTwo(bounds.Dx())
+	height := nextPowerOfTwo(bounds.Dy())
+
+	bgSpectrum, err := forwardSpectrum(ctx, grayValues(background), bounds.Dx(), bounds.Dy(), width, height, workers)
+	if err != nil {
+		return nil, err
+	}
+	tplSpectrum, err := forwardSpectrum(ctx, template, tplWidth, tplHeight, width, height, workers)
+	if err != nil {
+		return nil, err
+	}
+
+	return correlateSpectra(ctx, bgSpectrum, tplSpectrum, width, height, resultWidth, resultHeight, workers)
+}
@@ -0,0 +1,123 @@
+package ddddgocr
+
+import (
+	"context"
+	"image"
+	"math"
+	"math/cmplx"
+	"math/rand"
+	"testing"
+)
+
+// 直接按定义计算的离散傅里叶变换
+func naiveDFT(a []complex128) []complex128 {
+	n := len(a)
+	out := make([]complex128, n)
+	for k := range n {
+		for j, v := range a {
+			out[k] += v * cmplx.Rect(1, -2*math.Pi*float64(j*k)/float64(n))
+		}
+	}
+	return out
+}
+
+func randomComplex(rng *rand.Rand, n int) []complex128 {
+	a := make([]complex128, n)
+	for i := range a {
+		a[i] = complex(rng.Float64()*2-1, rng.Float64()*2-1)
+	}
+	return a
+}
+
+func randomGray(rng *rand.Rand, w, h int) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(rng.Intn(256))
+	}
+	return img
+}
+
+func TestFFTMatchesDFT(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, n := range []int{1, 2, 4, 8, 32} {
+		a := randomComplex(rng, n)
+		want := naiveDFT(a)
+		got := append([]complex128(nil), a...)
+		newFFTPlan(n, false).transform(got)
+		for k := range n {
+			if cmplx.Abs(got[k]-want[k]) > 1e-9 {
+				t.Fatalf("n=%d: X[%d] = %v, want %v", n, k, got[k], want[k])
+			}
+		}
+	}
+}
+
+func TestFFTRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	tests := []struct {
+		w, h, workers int
+	}{
+		{1, 1, 1},
+		{2, 1, 1},
+		{8, 4, 1},
+		{16, 32, 3},
+		{64, 8, 4},
+	}
+	for _, tt := range tests {
+		data := randomComplex(rng, tt.w*tt.h)
+		got := append([]complex128(nil), data...)
+		if err := fft2D(context.Background(), got, tt.w, tt.h, false, tt.workers); err != nil {
+			t.Fatal(err)
+		}
+		if err := fft2D(context.Background(), got, tt.w, tt.h, true, tt.workers); err != nil {
+			t.Fatal(err)
+		}
+
+		// 逆变换未除以长度
+		scale := complex(1/float64(tt.w*tt.h), 0)
+		for i := range data {
+			if cmplx.Abs(got[i]*scale-data[i]) > 1e-9 {
+				t.Fatalf("%dx%d: x[%d] = %v, want %v", tt.w, tt.h, i, got[i]*scale, data[i])
+			}
+		}
+	}
+}
+
+func TestCorrelateFFT(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	tests := []struct {
+		bgWidth, bgHeight, tplWidth, tplHeight int
+	}{
+		{37, 23, 5, 7},
+		{33, 17, 9, 3},
+		{13, 11, 13, 11},
+		{21, 19, 1, 1},
+		{65, 5, 31, 5},
+	}
+	for _, tt := range tests {
+		background := randomGray(rng, tt.bgWidth, tt.bgHeight)
+		template := make([]float64, tt.tplWidth*tt.tplHeight)
+		for i := range template {
+			template[i] = rng.Float64()*2 - 1
+		}
+		resultWidth, resultHeight := tt.bgWidth-tt.tplWidth+1, tt.bgHeight-tt.tplHeight+1
+
+		got, err := correlateFFT(context.Background(), background, template, tt.tplWidth, tt.tplHeight, resultWidth, resultHeight, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for y := range resultHeight {
+			for x := range resultWidth {
+				var want float64
+				for j := range tt.tplHeight {
+					for i := range tt.tplWidth {
+						want += float64(background.GrayAt(x+i, y+j).Y) * template[j*tt.tplWidth+i]
+					}
+				}
+				if math.Abs(got[y][x]-want) > 1e-6 {
+					t.Fatalf("bg %dx%d, tpl %dx%d: corr[%d][%d] = %v, want %v", tt.bgWidth, tt.bgHeight, tt.tplWidth, tt.tplHeight, y, x, got[y][x], want)
+				}
+			}
+		}
+	}
+}
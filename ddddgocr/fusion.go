@@ -0,0 +1,125 @@
+package ddddgocr
+
+import (
+	"math"
+	"sort"
+)
+
+// FusionPolicy 增强匹配中多个策略结果的融合方式
+type FusionPolicy int
+
+const (
+	FusionConsensus FusionPolicy = iota // 位置一致的结果聚为一类，按归一化得分与策略可靠度加权，取权重最大的一类
+	FusionLeftmost                      // 选择X1最小的正值结果，不考虑得分
+)
+
+// DefaultStrategyWeights 各策略的默认可靠度权重
+func DefaultStrategyWeights() map[Strategy]float64 {
+	return map[Strategy]float64{
+		StrategyGray:       1.0,
+		StrategyEdgeLow:    0.8,
+		StrategyEdgeMid:    0.9,
+		StrategyDifference: 0.5,
+		StrategySIFT:       0.9,
+		StrategyORB:        0.8,
+		StrategyContour:    0.7,
+	}
+}
+
+// 结果的融合权重：得分截断到[0, 1]后乘以策略可靠度，未设置的策略可靠度为1；
+// 模板策略的得分为相关系数，差分策略为列边缘峰值的突出程度，轮廓策略为形状相似度，均已归一化到[0, 1]
+func (o *Options) fusionWeight(result *SlideResult) float64 {
+	reliability, ok := o.StrategyWeights[result.Strategy]
+	if !ok {
+		reliability = 1
+	}
+	return math.Max(0, math.Min(1, result.Score)) * reliability
+}
+
+// FuseResults 融合增强匹配各策略的结果（均已平移到背景坐标），X1、Y1相差均不超过FusionTolerance的结果为一类；
+// 最终结果的Agreement为其所在类的权重占比，候选位置汇总自全部结果；results不能为空
+func FuseResults(results []*SlideResult, o *Options) *SlideResult {
+	// 按权重降序聚类，每类以权重最大的结果为中心
+	type cluster struct {
+		members []*SlideResult
+		weight  float64
+	}
+	sorted := make([]*SlideResult, len(results))
+	copy(sorted, results)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return o.fusionWeight(sorted[i]) > o.fusionWeight(sorted[j])
+	})
+
+	clusters := make([]*cluster, 0)
+	clusterOf := make(map[*SlideResult]*cluster)
+	var total float64
+	for _, result := range sorted {
+		weight := o.fusionWeight(result)
+		total += weight
+
+		var joined *cluster
+		for _, c := range clusters {
+			center := c.members[0]
+			if abs(result.X1-center.X1) <= o.FusionTolerance && abs(result.Y1-center.Y1) <= o.FusionTolerance {
+				joined = c
+				break
+			}
+		}
+		if joined == nil {
+			joined = &cluster{}
+			clusters = append(clusters, joined)
+		}
+		joined.members = append(joined.members, result)
+		joined.weight += weight
+		clusterOf[result] = joined
+	}
+
+	var best *SlideResult
+	switch o.Fusion {
+	case FusionLeftmost:
+		// 优先选择X1 > 0的结果，没有时选择第一个
+		for _, result := range results {
+			if result.X1 > 0 && (best == nil || result.X1 < best.X1) {
+				best = result
+			}
+		}
+		if best == nil {
+			best = results[0]
+		}
+	default:
+		// 权重最大的一类，取类中心即类内权重最大的结果
+		var chosen *cluster
+		for _, c := range clusters {
+			if chosen == nil || c.weight > chosen.weight {
+				chosen = c
+			}
+		}
+		best = chosen.members[0]
+	}
+
+	best.Agreement = 1
+	if total > 0 {
+		best.Agreement = clusterOf[best].weight / total
+	}
+
+	// 汇总各策略的候选位置
+	candidates := make([]SlideCandidate, 0)
+	for _, result := range results {
+		candidates = append(candidates, result.Candidates...)
+	}
+	first := SlideCandidate{SlideBBox: best.SlideBBox, Score: best.Score, Strategy: best.Strategy}
+	if len(best.Candidates) > 0 {
+		first = best.Candidates[0]
+	}
+	best.Candidates = RankCandidates(first, candidates, o.TopK)
+
+	return best
+}
+
+// 整数绝对值
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,134 @@
+package ddddgocr
+
+import (
+	"context"
+	"image"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func fusionInput(strategy Strategy, x, y int, score float64, withCandidate bool) *SlideResult {
+	bbox := SlideBBox{X1: x, Y1: y, X2: x + 40, Y2: y + 40}
+	result := &SlideResult{SlideBBox: bbox, Score: score, Strategy: strategy}
+	if withCandidate {
+		result.Candidates = []SlideCandidate{{SlideBBox: bbox, Score: score, Strategy: strategy}}
+	}
+	return result
+}
+
+func TestFuseResults(t *testing.T) {
+	tests := []struct {
+		name      string
+		results   []*SlideResult
+		weights   map[Strategy]float64
+		wantX     int
+		wantAgree float64
+	}{
+		{
+			// 两个一致的中等得分结果胜过单个高分结果：0.5·1 + 0.6·0.8 > 0.9·1
+			name: "consensus beats single score",
+			results: []*SlideResult{
+				fusionInput(StrategyGray, 20, 10, 0.9, true),
+				fusionInput(StrategyEdgeLow, 150, 12, 0.6, true),
+				fusionInput(StrategyEdgeMid, 152, 10, 0.5, true),
+			},
+			wantX:     150,
+			wantAgree: (0.6*0.8 + 0.5*0.9) / (0.9 + 0.6*0.8 + 0.5*0.9),
+		},
+		{
+			// 可靠度较低的策略即使得分更高也不占优
+			name: "reliability weighting",
+			results: []*SlideResult{
+				fusionInput(StrategyDifference, 60, 0, 0.8, true),
+				fusionInput(StrategyGray, 180, 30, 0.5, true),
+			},
+			wantX:     180,
+			wantAgree: 0.5 / (0.8*0.5 + 0.5),
+		},
+		{
+			name: "custom weights",
+			results: []*SlideResult{
+				fusionInput(StrategyDifference, 60, 0, 0.8, true),
+				fusionInput(StrategyGray, 180, 30, 0.5, true),
+			},
+			weights:   map[Strategy]float64{StrategyDifference: 1, StrategyGray: 1},
+			wantX:     60,
+			wantAgree: 0.8 / 1.3,
+		},
+		{
+			// 得分截断到[0, 1]，超出范围的得分不会压倒其他策略
+			name: "score clamped",
+			results: []*SlideResult{
+				fusionInput(StrategyContour, 90, 20, 5, true),
+				fusionInput(StrategyGray, 200, 20, 0.95, true),
+			},
+			wantX:     200,
+			wantAgree: 0.95 / (0.7 + 0.95),
+		},
+		{
+			// 没有候选的结果以自身位置作为首个候选
+			name: "result without candidates",
+			results: []*SlideResult{
+				fusionInput(StrategyComparison, 70, 5, 0.8, false),
+				fusionInput(StrategyGray, 200, 20, 0.3, true),
+			},
+			wantX:     70,
+			wantAgree: 0.8 / 1.1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := NewOptions()
+			if tt.weights != nil {
+				o.StrategyWeights = tt.weights
+			}
+			best := FuseResults(tt.results, o)
+			if best.X1 != tt.wantX {
+				t.Fatalf("X1 = %d, want %d", best.X1, tt.wantX)
+			}
+			if math.Abs(best.Agreement-tt.wantAgree) > 1e-9 {
+				t.Errorf("Agreement = %v, want %v", best.Agreement, tt.wantAgree)
+			}
+			if len(best.Candidates) == 0 || best.Candidates[0].X1 != tt.wantX {
+				t.Errorf("first candidate = %+v, want X1 %d", best.Candidates, tt.wantX)
+			}
+		})
+	}
+}
+
+// 差分策略的得分与相关系数同在[0, 1]内，缺口边缘突出时接近1
+func TestFindSlotByDifferenceScore(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	background := image.NewGray(image.Rect(0, 0, 200, 60))
+	for y := range 60 {
+		for x := range 200 {
+			v := 100 + rng.Intn(6)
+			if x >= 120 && x < 150 && y%4 < 2 {
+				v = 200
+			}
+			background.Pix[y*background.Stride+x] = uint8(v)
+		}
+	}
+	target := randomGray(rng, 30, 30)
+
+	result, err := FindSlotByDifference(context.Background(), background, target, NewOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result == nil {
+		t.Fatal("no result")
+	}
+	if result.X1 < 120 || result.X1 >= 150 {
+		t.Errorf("X1 = %d, want within [120, 150)", result.X1)
+	}
+	if result.Score < 0.5 || result.Score > 1 {
+		t.Errorf("Score = %v, want within [0.5, 1]", result.Score)
+	}
+	for _, c := range result.Candidates {
+		if c.Score < 0 || c.Score > 1 {
+			t.Errorf("candidate score %v outside [0, 1]", c.Score)
+		}
+	}
+}
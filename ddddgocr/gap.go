@@ -0,0 +1,216 @@
+package ddddgocr
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"math"
+	"sort"
+	"time"
+)
+
+// GapMatch 缺口定位，只根据背景图片寻找加深或描边的缺口，不需要滑块图片
+func GapMatch(backgroundImageData []byte, opts ...Option) (*SlideResult, error) {
+	return GapMatchContext(context.Background(), backgroundImageData, opts...)
+}
+
+// GapMatchContext 可取消的缺口定位
+func GapMatchContext(ctx context.Context, backgroundImageData []byte, opts ...Option) (*SlideResult, error) {
+	start := time.Now()
+
+	// 解码图像
+	backgroundImg, _, err := image.Decode(bytes.NewReader(backgroundImageData))
+	if err != nil {
+		return nil, &DecodeError{Image: RoleBackground, Err: err}
+	}
+
+	return gapMatch(ctx, start, backgroundImg, NewOptions(opts...))
+}
+
+// GapMatchImage 对已解码图像进行缺口定位
+func GapMatchImage(ctx context.Context, backgroundImg image.Image, opts ...Option) (*SlideResult, error) {
+	return gapMatch(ctx, time.Now(), originImage(backgroundImg), NewOptions(opts...))
+}
+
+// 缺口定位流程，start为计时起点
+func gapMatch(ctx context.Context, start time.Time, backgroundImg image.Image, o *Options) (*SlideResult, error) {
+	gray, err := toGrayScale(ctx, backgroundImg, o.workerCount())
+	if err != nil {
+		return nil, err
+	}
+
+	// 局部均值，窗口远大于缺口，缺口内的均值接近周围的亮度
+	mean, err := boxMean(ctx, gray, o.GapMaxSize, o.workerCount())
+	if err != nil {
+		return nil, err
+	}
+
+	// 边缘检测
+	edges, err := cannyEdgeDetection(ctx, gray, o.Canny.Low, o.Canny.High, o.workerCount())
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := LocateGap(ctx, gray, mean, edges, o)
+	if err != nil {
+		return nil, err
+	}
+	return finishResult(result, start), nil
+}
+
+// 以(2·radius+1)²窗口求局部均值，窗口超出图像的部分不参与计算
+func boxMean(ctx context.Context, img *image.Gray, radius, workers int) (*image.Gray, error) {
+	img = cropGray(img, img.Bounds())
+	width, height := img.Bounds().Dx(), img.Bounds().Dy()
+	ii := newIntegralImage(img)
+
+	mean := image.NewGray(img.Bounds())
+	err := parallelRows(ctx, 0, height, workers, func(y int) {
+		y0, y1 := max(0, y-radius), min(height, y+radius+1)
+		for x := range width {
+			x0, x1 := max(0, x-radius), min(width, x+radius+1)
+			sum, _ := ii.window(x0, y0, x1-x0, y1-y0)
+			mean.Pix[y*mean.Stride+x] = uint8(math.Round(float64(sum) / float64((x1-x0)*(y1-y0))))
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return mean, nil
+}
+
+// LocateGap 只根据背景寻找缺口，gray为背景灰度图，mean为边长2·GapMaxSize+1窗口的局部均值，
+// edges为Canny边缘；候选区域为明显暗于局部均值的连通区域与被边缘包围的封闭区域，
+// 得分为加深程度、轮廓完整度与形状规整度的平均值。两种引擎完成滤波与边缘检测后共用该分析
+func LocateGap(ctx context.Context, gray, mean, edges *image.Gray, o *Options) (*SlideResult, error) {
+	gray = cropGray(gray, gray.Bounds())
+	mean = cropGray(mean, mean.Bounds())
+	edges = cropGray(edges, edges.Bounds())
+	width, height := gray.Bounds().Dx(), gray.Bounds().Dy()
+
+	// 加深区域，8连通
+	dark, err := labelRegions(ctx, width, height, true, func(i int) bool {
+		return float64(gray.Pix[i]) < float64(mean.Pix[i])*(1-o.GapDarkening)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 边缘包围的封闭区域，4连通，与边缘像素8连通的区域互不相连
+	enclosed, err := labelRegions(ctx, width, height, false, func(i int) bool {
+		return edges.Pix[i] == 0
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]SlideCandidate, 0)
+	for _, region := range append(dark, enclosed...) {
+		score, ok := gapScore(region, gray, mean, edges, o)
+		if !ok {
+			continue
+		}
+		candidates = append(candidates, SlideCandidate{
+			SlideBBox: SlideBBox{
+				X1:   region.bounds.Min.X,
+				Y1:   region.bounds.Min.Y,
+				X2:   region.bounds.Max.X,
+				Y2:   region.bounds.Max.Y,
+				SubX: float64(region.bounds.Min.X),
+				SubY: float64(region.bounds.Min.Y),
+				Area: len(region.pixels),
+			},
+			Score:    score,
+			Strategy: StrategyGap,
+		})
+	}
+	if len(candidates) == 0 {
+		return nil, ErrNoMatch
+	}
+
+	// 两种来源常得到同一缺口，重叠过半时只保留得分较高者
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
+	kept := make([]SlideCandidate, 0, len(candidates))
+	for _, c := range candidates {
+		duplicate := false
+		for _, k := range kept {
+			if boxIoU(c.SlideBBox, k.SlideBBox) > 0.5 {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			kept = append(kept, c)
+		}
+	}
+
+	best := kept[0]
+	if best.Score < o.GapMinScore {
+		return nil, &LowQualityError{Score: best.Score, Threshold: o.GapMinScore, Strategy: StrategyGap}
+	}
+
+	result := &SlideResult{
+		SlideBBox:  best.SlideBBox,
+		Score:      best.Score,
+		Margin:     best.Score,
+		Strategy:   StrategyGap,
+		Candidates: kept[:min(o.TopK, len(kept))],
+	}
+	if len(kept) > 1 {
+		result.Margin -= kept[1].Score
+	}
+	return result, nil
+}
+
+// 区域作为缺口的得分，尺寸不符、接触图像边界或形状过于稀疏的区域不作为候选
+func gapScore(region pixelRegion, gray, mean, edges *image.Gray, o *Options) (float64, bool) {
+	bounds := region.bounds
+	imageBounds := gray.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w < o.GapMinSize || h < o.GapMinSize || w > o.GapMaxSize || h > o.GapMaxSize {
+		return 0, false
+	}
+	if bounds.Min.X == 0 || bounds.Min.Y == 0 || bounds.Max.X == imageBounds.Max.X || bounds.Max.Y == imageBounds.Max.Y {
+		return 0, false
+	}
+
+	// 形状规整度：填充率与长宽比
+	fill := float64(len(region.pixels)) / float64(w*h)
+	if fill < 0.4 {
+		return 0, false
+	}
+	regularity := fill * float64(min(w, h)) / float64(max(w, h))
+
+	// 加深程度：相对局部均值的平均降幅，降至一半即为满分
+	width := imageBounds.Dx()
+	var darkening float64
+	boundary, outlined := 0, 0
+	for _, i := range region.pixels {
+		if m := float64(mean.Pix[i]); m > 0 {
+			darkening += math.Max(0, (m-float64(gray.Pix[i]))/m)
+		}
+
+		// 轮廓完整度：区域边界像素中3x3邻域内存在边缘的比例
+		x, y := i%width, i/width
+		if region.labels[i-1] == region.id && region.labels[i+1] == region.id &&
+			region.labels[i-width] == region.id && region.labels[i+width] == region.id {
+			continue
+		}
+		boundary++
+	neighbors:
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				if edges.Pix[(y+dy)*width+x+dx] != 0 {
+					outlined++
+					break neighbors
+				}
+			}
+		}
+	}
+	contrast := math.Min(1, darkening/float64(len(region.pixels))/0.5)
+	outline := float64(outlined) / float64(max(boundary, 1))
+
+	return (contrast + outline + regularity) / 3, true
+}
@@ -0,0 +1,49 @@
+package ddddgocr
+
+import "image"
+
+// 积分图（summed-area table），记录左上区域的像素和与平方和
+type integralImage struct {
+	stride int
+	sum    []int64
+	sqSum  []int64
+}
+
+// 构建灰度图的积分图，sum[(y)*stride+x]为[0,x)×[0,y)内的像素和
+func newIntegralImage(img *image.Gray) *integralImage {
+	bounds := img.Bounds()
+	width := bounds.Dx()
+	height := bounds.Dy()
+	stride := width + 1
+
+	ii := &integralImage{
+		stride: stride,
+		sum:    make([]int64, stride*(height+1)),
+		sqSum:  make([]int64, stride*(height+1)),
+	}
+
+	for y := range height {
+		var rowSum, rowSqSum int64
+		row := img.Pix[img.PixOffset(bounds.Min.X, bounds.Min.Y+y):]
+		for x := range width {
+			v := int64(row[x])
+			rowSum += v
+			rowSqSum += v * v
+			idx := (y+1)*stride + x + 1
+			ii.sum[idx] = ii.sum[idx-stride] + rowSum
+			ii.sqSum[idx] = ii.sqSum[idx-stride] + rowSqSum
+		}
+	}
+
+	return ii
+}
+
+// 以(x, y)为左上角、宽w高h的窗口内像素和与平方和
+func (ii *integralImage) window(x, y, w, h int) (sum, sqSum int64) {
+	a := y*ii.stride + x
+	b := a + w
+	c := (y+h)*ii.stride + x
+	d := c + w
+	return ii.sum[d] - ii.sum[b] - ii.sum[c] + ii.sum[a],
+		ii.sqSum[d] - ii.sqSum[b] - ii.sqSum[c] + ii.sqSum[a]
+}
@@ -0,0 +1,169 @@
+package ddddgocr
+
+import (
+	"context"
+	"image"
+	"math"
+)
+
+// 由RGBA图像的透明通道生成匹配掩码，不透明像素为255，
+// 图像完全不透明或完全透明时无需掩码，返回nil
+func alphaMask(img *image.RGBA) *image.Alpha {
+	bounds := img.Bounds()
+	mask := image.NewAlpha(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+
+	opaque := 0
+	for y := range bounds.Dy() {
+		for x := range bounds.Dx() {
+			if img.RGBAAt(bounds.Min.X+x, bounds.Min.Y+y).A != 0 {
+				mask.Pix[mask.PixOffset(x, y)] = 255
+				opaque++
+			}
+		}
+	}
+
+	if opaque == 0 || opaque == bounds.Dx()*bounds.Dy() {
+		return nil
+	}
+	return mask
+}
+
+// 掩码标准化交叉相关，只统计掩码内的像素，与OpenCV带掩码的TM_CCOEFF_NORMED一致：
+// Σ(b-μb)(t-μt) / sqrt(Σ(b-μb)² · Σ(t-μt)²)，μb、μt均为掩码内的均值
+func matchTemplateMasked(ctx context.Context, background, template *image.Gray, mask *image.Alpha, o *Options) ([][]float64, error) {
+	bgBounds := background.Bounds()
+	tplBounds := template.Bounds()
+
+	bgWidth := bgBounds.Dx()
+	bgHeight := bgBounds.Dy()
+	tplWidth := tplBounds.Dx()
+	tplHeight := tplBounds.Dy()
+
+	resultWidth := bgWidth - tplWidth + 1
+	resultHeight := bgHeight - tplHeight + 1
+
+	if resultWidth <= 0 || resultHeight <= 0 {
+		return nil, nil
+	}
+
+	result := make([][]float64, resultHeight)
+	for i := range result {
+		result[i] = make([]float64, resultWidth)
+	}
+
+	// 掩码内模板的均值
+	var templateSum float64
+	var n int64
+	for y := range tplHeight {
+		for x := range tplWidth {
+			if mask.AlphaAt(x, y).A != 0 {
+				templateSum += getGrayValue(template, x, y)
+				n++
+			}
+		}
+	}
+	if n == 0 {
+		return result, nil
+	}
+	templateMean := templateSum / float64(n)
+
+	// 掩码内像素相对背景的偏移与零均值模板值，掩码外的值为零
+	type maskPixel struct {
+		offset int
+		value  float64
+	}
+	pixels := make([]maskPixel, 0, n)
+	zeroMean := make([]float64, tplWidth*tplHeight)
+	maskValues := make([]float64, tplWidth*tplHeight)
+	var templateSumSq float64
+	for y := range tplHeight {
+		for x := range tplWidth {
+			if mask.AlphaAt(x, y).A == 0 {
+				continue
+			}
+			diff := getGrayValue(template, x, y) - templateMean
+			templateSumSq += diff * diff
+			pixels = append(pixels, maskPixel{offset: y*background.Stride + x, value: diff})
+			zeroMean[y*tplWidth+x] = diff
+			maskValues[y*tplWidth+x] = 1
+		}
+	}
+	templateStd := math.Sqrt(templateSumSq)
+
+	// 频域分别求掩码内的Σb、Σb²与Σb·(t-μt)，前两项为整数，取整后可精确计算方差；
+	// 掩码像素稠密，逐点计算开销大，FFTAuto下总是使用FFT
+	var windowSums, windowSqSums, crossTerms [][]float64
+	if o.FFT != FFTNever {
+		width := nextPowerOfTwo(bgWidth)
+		height := nextPowerOfTwo(bgHeight)
+		workers := o.workerCount()
+
+		values := grayValues(background)
+		squares := make([]float64, len(values))
+		for i, v := range values {
+			squares[i] = v * v
+		}
+
+		bgSpectrum, err := forwardSpectrum(ctx, values, bgWidth, bgHeight, width, height, workers)
+		if err != nil {
+			return nil, err
+		}
+		sqSpectrum, err := forwardSpectrum(ctx, squares, bgWidth, bgHeight, width, height, workers)
+		if err != nil {
+			return nil, err
+		}
+		maskSpectrum, err := forwardSpectrum(ctx, maskValues, tplWidth, tplHeight, width, height, workers)
+		if err != nil {
+			return nil, err
+		}
+		tplSpectrum, err := forwardSpectrum(ctx, zeroMean, tplWidth, tplHeight, width, height, workers)
+		if err != nil {
+			return nil, err
+		}
+
+		if windowSums, err = correlateSpectra(ctx, bgSpectrum, maskSpectrum, width, height, resultWidth, resultHeight, workers); err != nil {
+			return nil, err
+		}
+		if windowSqSums, err = correlateSpectra(ctx, sqSpectrum, maskSpectrum, width, height, resultWidth, resultHeight, workers); err != nil {
+			return nil, err
+		}
+		if crossTerms, err = correlateSpectra(ctx, bgSpectrum, tplSpectrum, width, height, resultWidth, resultHeight, workers); err != nil {
+			return nil, err
+		}
+	}
+
+	err := parallelRows(ctx, 0, resultHeight, o.workerCount(), func(y int) {
+		for x := range resultWidth {
+			var windowSum, windowSqSum int64
+			var correlation float64
+			if crossTerms != nil {
+				windowSum = int64(math.Round(windowSums[y][x]))
+				windowSqSum = int64(math.Round(windowSqSums[y][x]))
+				correlation = crossTerms[y][x]
+			} else {
+				base := background.PixOffset(bgBounds.Min.X+x, bgBounds.Min.Y+y)
+				for _, p := range pixels {
+					v := background.Pix[base+p.offset]
+					windowSum += int64(v)
+					windowSqSum += int64(v) * int64(v)
+					correlation += float64(v) * p.value
+				}
+			}
+
+			// 与无掩码时相同，方差分子为整数，为零时可精确判断
+			varianceNum := n*windowSqSum - windowSum*windowSum
+			if varianceNum <= 0 || templateStd == 0 {
+				result[y][x] = 0
+				continue
+			}
+			windowStd := math.Sqrt(float64(varianceNum) / float64(n))
+
+			result[y][x] = correlation / (windowStd * templateStd)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,444 @@
+package ddddgocr
+
+import (
+	"image"
+	"runtime"
+)
+
+// FFTMode 模板匹配是否使用FFT计算互相关（仅纯Go引擎）
+type FFTMode int
+
+const (
+	FFTAuto   FFTMode = iota // 背景宽度超过FFTMinWidth或使用透明通道掩码时使用
+	FFTAlways                // 总是使用
+	FFTNever                 // 总是使用空间域逐点计算
+)
+
+// SearchAxis 滑块移动方向，用于约束模板匹配的搜索范围
+type SearchAxis int
+
+const (
+	AxisFree       SearchAxis = iota // 在整个背景中二维搜索
+	AxisHorizontal                   // 横向滑块，固定行带，只沿X方向搜索
+	AxisVertical                     // 纵向滑块，固定列带，只沿Y方向搜索
+)
+
+// OffsetFromPiece 以滑块自身在目标图像中的偏移（裁剪透明区域得到）作为固定的行或列
+const OffsetFromPiece = -1
+
+// CannyThreshold Canny边缘检测的双阈值
+type CannyThreshold struct {
+	Low, High float64
+}
+
+// Options 匹配参数，两种引擎含义一致
+type Options struct {
+	Canny    CannyThreshold // 标准/简单匹配的Canny阈值
+	CannyLow CannyThreshold // 增强匹配低阈值边缘策略的Canny阈值
+	CannyMid CannyThreshold // 增强匹配中阈值边缘策略的Canny阈值
+
+	MinScore        float64 // 标准/简单匹配的最低得分，低于该值视为匹配失败
+	GrayMinScore    float64 // 增强匹配灰度策略的采纳得分，需高于该值
+	EdgeLowMinScore float64 // 增强匹配低阈值边缘策略的采纳得分，需高于该值
+	EdgeMidMinScore float64 // 增强匹配中阈值边缘策略的采纳得分，需高于该值
+
+	SlotEdgeFloor float64 // 差分寻找缺口策略的最低列边缘强度
+
+	DiffThreshold int // 比较模式的像素差异阈值（RGB平均差）
+	DiffRunLength int // 比较模式中差异区域作为坑位候选的最小宽高
+	DiffOpenSize  int // 比较模式去噪开运算的结构元素边长，去除JPEG噪点等零散差异，不大于1时不做开运算
+
+	TopK int // 结果中返回的候选位置数量
+
+	FFT         FFTMode // 互相关计算方式
+	FFTMinWidth int     // FFTAuto下启用FFT的背景宽度下限
+
+	Workers int // 图像处理的并行协程数，0为GOMAXPROCS，1为顺序执行（仅纯Go引擎）
+
+	AlphaMask bool // 以滑块的透明通道作为匹配掩码，透明角落不参与相关计算
+
+	Axis          SearchAxis // 搜索方向约束
+	AxisOffset    int        // 固定的行（横向）或列（纵向），OffsetFromPiece为滑块自身偏移
+	AxisTolerance int        // 固定行或列允许的偏差像素数
+
+	SubPixel SubPixelMode // 相关峰值的亚像素细化方式
+
+	ScaleMin  float64 // 标准/简单匹配尺度搜索的最小缩放倍数
+	ScaleMax  float64 // 标准/简单匹配尺度搜索的最大缩放倍数，与ScaleMin均为1时不搜索
+	ScaleStep float64 // 尺度粗搜索的步长，细搜索步长为其1/4
+
+	AngleMax  float64 // 标准匹配中滑块旋转角度的搜索范围（度），在[-AngleMax, AngleMax]内搜索，不大于0时不旋转
+	AngleStep float64 // 角度粗搜索的步长，细搜索步长为其1/4
+
+	FeatureRatio      float64 // 特征匹配比值检验的阈值，最近邻与次近邻描述子距离之比需低于该值
+	FeatureTolerance  float64 // RANSAC内点允许的重投影误差（像素）
+	FeatureMinInliers int     // 特征匹配结果所需的最少内点数
+	FeatureSimilarity bool    // RANSAC估计相似变换（平移、旋转与缩放），否则只估计平移
+
+	GapMinSize   int     // 缺口定位的最小缺口边长
+	GapMaxSize   int     // 缺口定位的最大缺口边长，同时为局部均值窗口的半径
+	GapDarkening float64 // 缺口像素相对局部均值的最低降幅比例
+	GapMinScore  float64 // 缺口定位的最低得分
+
+	Fusion          FusionPolicy         // 增强匹配多策略结果的融合方式
+	FusionTolerance int                  // 视为同一位置的最大X、Y偏差像素数
+	StrategyWeights map[Strategy]float64 // 各策略的可靠度权重，未列出的策略为1
+
+	RotateTrackLength float64 // 旋转验证码的滑块轨道长度，大于0时计算滑块移动距离
+	RotateFullAngle   float64 // 滑块移动完整轨道时内圈旋转的角度
+
+	ClickMaxAngle   float64 // 点选验证码图标的最大旋转角度（度）
+	ClickAngleStep  float64 // 图标旋转角度的搜索步长，不大于0时不旋转
+	ClickForeground int     // 不透明的提示条中前景与背景色的最低RGB平均差
+
+	TileRows    int // 拼图交换验证码的行数
+	TileColumns int // 拼图交换验证码的列数
+
+	ContourClose    int     // 轮廓匹配中闭合边缘缺口的膨胀核边长，不大于1时不膨胀
+	ContourMinScore float64 // 轮廓匹配的最低得分
+
+	DecoyCandidates int // 滑块带透明通道时按缺口特征重新排序的候选数量，不大于1时直接取相关系数最大的位置
+}
+
+// Option 修改匹配参数的函数
+type Option func(*Options)
+
+// DefaultOptions 默认匹配参数
+func DefaultOptions() Options {
+	return Options{
+		Canny:    CannyThreshold{Low: 100, High: 200},
+		CannyLow: CannyThreshold{Low: 30, High: 80},
+		CannyMid: CannyThreshold{Low: 50, High: 150},
+
+		MinScore:        0.3,
+		GrayMinScore:    0.6,
+		EdgeLowMinScore: 0.3,
+		EdgeMidMinScore: 0.2,
+
+		SlotEdgeFloor: 10.0,
+
+		DiffThreshold: 80,
+		DiffRunLength: 5,
+		DiffOpenSize:  3,
+
+		TopK: 1,
+
+		FFT:         FFTAuto,
+		FFTMinWidth: 600,
+
+		Workers: 0,
+
+		AlphaMask: true,
+
+		Axis:          AxisFree,
+		AxisOffset:    OffsetFromPiece,
+		AxisTolerance: 4,
+
+		SubPixel: SubPixelNone,
+
+		ScaleMin:  1,
+		ScaleMax:  1,
+		ScaleStep: 0.1,
+
+		AngleMax:  0,
+		AngleStep: 2,
+
+		FeatureRatio:      0.75,
+		FeatureTolerance:  3,
+		FeatureMinInliers: 4,
+		FeatureSimilarity: false,
+
+		GapMinSize:   30,
+		GapMaxSize:   120,
+		GapDarkening: 0.25,
+		GapMinScore:  0.7,
+
+		Fusion:          FusionConsensus,
+		FusionTolerance: 5,
+		StrategyWeights: DefaultStrategyWeights(),
+
+		RotateTrackLength: 0,
+		RotateFullAngle:   360,
+
+		ClickMaxAngle:   30,
+		ClickAngleStep:  10,
+		ClickForeground: 40,
+
+		TileRows:    3,
+		TileColumns: 3,
+
+		ContourClose:    3,
+		ContourMinScore: 0.3,
+
+		DecoyCandidates: 5,
+	}
+}
+
+// NewOptions 在默认参数上依次应用opts
+func NewOptions(opts ...Option) *Options {
+	o := DefaultOptions()
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&o)
+		}
+	}
+	if o.TopK < 1 {
+		o.TopK = 1
+	}
+	return &o
+}
+
+// WithOptions 整体替换匹配参数
+func WithOptions(options Options) Option {
+	return func(o *Options) {
+		*o = options
+	}
+}
+
+// WithCanny 设置标准/简单匹配的Canny阈值
+func WithCanny(low, high float64) Option {
+	return func(o *Options) {
+		o.Canny = CannyThreshold{Low: low, High: high}
+	}
+}
+
+// WithEnhancedCanny 设置增强匹配两种边缘策略的Canny阈值
+func WithEnhancedCanny(low, mid CannyThreshold) Option {
+	return func(o *Options) {
+		o.CannyLow = low
+		o.CannyMid = mid
+	}
+}
+
+// WithMinScore 设置标准/简单匹配的最低得分
+func WithMinScore(score float64) Option {
+	return func(o *Options) {
+		o.MinScore = score
+	}
+}
+
+// WithStrategyMinScores 设置增强匹配各模板策略的采纳得分
+func WithStrategyMinScores(gray, edgeLow, edgeMid float64) Option {
+	return func(o *Options) {
+		o.GrayMinScore = gray
+		o.EdgeLowMinScore = edgeLow
+		o.EdgeMidMinScore = edgeMid
+	}
+}
+
+// WithSlotEdgeFloor 设置差分寻找缺口策略的最低列边缘强度
+func WithSlotEdgeFloor(floor float64) Option {
+	return func(o *Options) {
+		o.SlotEdgeFloor = floor
+	}
+}
+
+// WithDiff 设置比较模式的像素差异阈值与坑位候选的最小宽高
+func WithDiff(threshold, runLength int) Option {
+	return func(o *Options) {
+		o.DiffThreshold = threshold
+		o.DiffRunLength = runLength
+	}
+}
+
+// WithDiffOpening 设置比较模式去噪开运算的结构元素边长，不大于1时不做开运算；坑位细于该边长时需调小
+func WithDiffOpening(size int) Option {
+	return func(o *Options) {
+		o.DiffOpenSize = size
+	}
+}
+
+// WithTopK 设置返回的候选位置数量，相邻候选间距至少为滑块尺寸
+func WithTopK(k int) Option {
+	return func(o *Options) {
+		o.TopK = k
+	}
+}
+
+// WithFFT 设置互相关计算方式，minWidth为FFTAuto下启用FFT的背景宽度下限
+func WithFFT(mode FFTMode, minWidth int) Option {
+	return func(o *Options) {
+		o.FFT = mode
+		o.FFTMinWidth = minWidth
+	}
+}
+
+// 背景宽度为width时是否使用FFT
+func (o *Options) useFFT(width int) bool {
+	switch o.FFT {
+	case FFTAlways:
+		return true
+	case FFTNever:
+		return false
+	default:
+		return width > o.FFTMinWidth
+	}
+}
+
+// WithWorkers 设置图像处理的并行协程数，0为GOMAXPROCS，1为顺序执行
+func WithWorkers(n int) Option {
+	return func(o *Options) {
+		o.Workers = n
+	}
+}
+
+// WithAlphaMask 设置是否以滑块的透明通道作为匹配掩码，关闭时透明像素按黑色参与相关计算
+func WithAlphaMask(enabled bool) Option {
+	return func(o *Options) {
+		o.AlphaMask = enabled
+	}
+}
+
+// WithAxis 设置搜索方向约束，offset为固定的行或列（OffsetFromPiece为滑块自身偏移），
+// tolerance为允许的偏差像素数
+func WithAxis(axis SearchAxis, offset, tolerance int) Option {
+	return func(o *Options) {
+		o.Axis = axis
+		o.AxisOffset = offset
+		o.AxisTolerance = tolerance
+	}
+}
+
+// WithSubPixel 设置相关峰值的亚像素细化方式，结果见SubX、SubY
+func WithSubPixel(mode SubPixelMode) Option {
+	return func(o *Options) {
+		o.SubPixel = mode
+	}
+}
+
+// WithScales 设置标准/简单匹配的尺度搜索范围与粗搜索步长，
+// 如背景为2倍图而滑块为1倍图时可设置为(1.5, 2.5, 0.1)
+func WithScales(minScale, maxScale, step float64) Option {
+	return func(o *Options) {
+		o.ScaleMin = minScale
+		o.ScaleMax = maxScale
+		o.ScaleStep = step
+	}
+}
+
+// WithAngles 设置标准匹配中滑块旋转角度的搜索范围与粗搜索步长，如滑块相对缺口旋转了几度时可设置为(6, 2)
+func WithAngles(maxAngle, step float64) Option {
+	return func(o *Options) {
+		o.AngleMax = maxAngle
+		o.AngleStep = step
+	}
+}
+
+// WithFeatureMatching 设置增强匹配特征策略的比值检验阈值、RANSAC内点误差与最少内点数，
+// similarity时估计相似变换，结果的Scale为估计的缩放倍数
+func WithFeatureMatching(ratio, tolerance float64, minInliers int, similarity bool) Option {
+	return func(o *Options) {
+		o.FeatureRatio = ratio
+		o.FeatureTolerance = tolerance
+		o.FeatureMinInliers = minInliers
+		o.FeatureSimilarity = similarity
+	}
+}
+
+// WithGap 设置缺口定位的缺口边长范围、加深判定的最低降幅比例与最低得分
+func WithGap(minSize, maxSize int, darkening, minScore float64) Option {
+	return func(o *Options) {
+		o.GapMinSize = minSize
+		o.GapMaxSize = maxSize
+		o.GapDarkening = darkening
+		o.GapMinScore = minScore
+	}
+}
+
+// WithFusion 设置增强匹配多策略结果的融合方式与视为同一位置的最大偏差像素数
+func WithFusion(policy FusionPolicy, tolerance int) Option {
+	return func(o *Options) {
+		o.Fusion = policy
+		o.FusionTolerance = tolerance
+	}
+}
+
+// WithStrategyWeights 设置各策略的可靠度权重，未列出的策略为1
+func WithStrategyWeights(weights map[Strategy]float64) Option {
+	return func(o *Options) {
+		o.StrategyWeights = weights
+	}
+}
+
+// WithRotateTrack 设置旋转验证码的滑块轨道长度与滑块移动完整轨道时内圈旋转的角度
+func WithRotateTrack(length, fullAngle float64) Option {
+	return func(o *Options) {
+		o.RotateTrackLength = length
+		o.RotateFullAngle = fullAngle
+	}
+}
+
+// WithClick 设置点选验证码图标的最大旋转角度、搜索步长与提示条前景的最低颜色差
+func WithClick(maxAngle, step float64, foreground int) Option {
+	return func(o *Options) {
+		o.ClickMaxAngle = maxAngle
+		o.ClickAngleStep = step
+		o.ClickForeground = foreground
+	}
+}
+
+// WithTileGrid 设置拼图交换验证码的行数与列数
+func WithTileGrid(rows, columns int) Option {
+	return func(o *Options) {
+		o.TileRows = rows
+		o.TileColumns = columns
+	}
+}
+
+// WithContour 设置轮廓匹配闭合边缘缺口的膨胀核边长与最低得分
+func WithContour(closeSize int, minScore float64) Option {
+	return func(o *Options) {
+		o.ContourClose = closeSize
+		o.ContourMinScore = minScore
+	}
+}
+
+// WithDecoyCandidates 设置标准匹配中按缺口特征重新排序的候选数量，用于排除与滑块形状不符的干扰缺口；
+// 重新排序后Score为综合得分而非相关系数，设置为0或1时关闭
+func WithDecoyCandidates(n int) Option {
+	return func(o *Options) {
+		o.DecoyCandidates = n
+	}
+}
+
+// SearchRegion 背景中参与模板匹配的区域，匹配位置的模板需完全落在该区域内；
+// piece为滑块自身在目标图像中的偏移，未知时为nil，此时OffsetFromPiece退化为二维搜索
+func (o *Options) SearchRegion(background image.Rectangle, template image.Point, piece *image.Point) image.Rectangle {
+	if o.Axis == AxisFree {
+		return background
+	}
+
+	offset := o.AxisOffset
+	if offset < 0 {
+		if piece == nil {
+			return background
+		}
+		offset = piece.Y
+		if o.Axis == AxisVertical {
+			offset = piece.X
+		}
+	}
+	tolerance := max(o.AxisTolerance, 0)
+
+	// 带宽为模板尺寸加两侧偏差，超出背景时向内平移
+	region := background
+	switch o.Axis {
+	case AxisHorizontal:
+		minY := max(background.Min.Y, min(background.Min.Y+offset-tolerance, background.Max.Y-template.Y))
+		region.Min.Y = minY
+		region.Max.Y = min(background.Max.Y, max(background.Min.Y+offset+tolerance+template.Y, minY+template.Y))
+	case AxisVertical:
+		minX := max(background.Min.X, min(background.Min.X+offset-tolerance, background.Max.X-template.X))
+		region.Min.X = minX
+		region.Max.X = min(background.Max.X, max(background.Min.X+offset+tolerance+template.X, minX+template.X))
+	}
+	return region
+}
+
+// 实际使用的并行协程数
+func (o *Options) workerCount() int {
+	if o.Workers > 0 {
+		return o.Workers
+	}
+	return runtime.GOMAXPROCS(0)
+}
@@ -0,0 +1,253 @@
+package ddddgocr
+
+import (
+	"context"
+	"image"
+	"math"
+	"math/bits"
+	"math/rand/v2"
+	"sort"
+)
+
+const (
+	orbPatchRadius   = 10  // BRIEF采样与方向计算的圆形区域半径
+	orbFastThreshold = 20  // FAST角点的灰度差阈值
+	orbMaxKeypoints  = 500 // 每张图像保留的最多关键点数
+)
+
+// FAST-9检测所用的半径为3的Bresenham圆
+var fastCircle = [16]image.Point{
+	{0, -3}, {1, -3}, {2, -2}, {3, -1}, {3, 0}, {3, 1}, {2, 2}, {1, 3},
+	{0, 3}, {-1, 3}, {-2, 2}, {-3, 1}, {-3, 0}, {-3, -1}, {-2, -2}, {-1, -3},
+}
+
+// BRIEF的256对采样点，以固定种子在圆形区域内按高斯分布生成；
+// 距中心不超过orbPatchRadius-1，旋转取整后仍在区域内
+var orbPattern = func() [256][2]image.Point {
+	rng := rand.New(rand.NewPCG(0x6f7262, 0))
+	point := func() image.Point {
+		for {
+			x := math.Round(rng.NormFloat64() * orbPatchRadius / 2)
+			y := math.Round(rng.NormFloat64() * orbPatchRadius / 2)
+			if math.Hypot(x, y) <= orbPatchRadius-1 {
+				return image.Pt(int(x), int(y))
+			}
+		}
+	}
+
+	var pattern [256][2]image.Point
+	for i := range pattern {
+		pattern[i] = [2]image.Point{point(), point()}
+	}
+	return pattern
+}()
+
+// ORB关键点与其256位描述子
+type orbFeature struct {
+	x, y       int
+	score      float64
+	descriptor [4]uint64
+}
+
+// ORB特征匹配：检测FAST角点并计算带方向的BRIEF描述子，按汉明距离做比值检验后以RANSAC估计滑块位置；
+// mask非空时只保留采样区域完全不透明的滑块关键点
+func orbFeatureMatch(ctx context.Context, background, target *image.Gray, mask *image.Alpha, targetY int, o *Options) (*SlideResult, error) {
+	targetFeatures, err := orbFeatures(ctx, target, mask, o.workerCount())
+	if err != nil || len(targetFeatures) == 0 {
+		return nil, err
+	}
+	backgroundFeatures, err := orbFeatures(ctx, background, nil, o.workerCount())
+	if err != nil || len(backgroundFeatures) < 2 {
+		return nil, err
+	}
+
+	// 暴力匹配最近邻与次近邻
+	matches := make([]FeatureMatch, 0)
+	for _, f := range targetFeatures {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		best, second := math.MaxInt, math.MaxInt
+		bestIndex := -1
+		for i, g := range backgroundFeatures {
+			d := hammingDistance(f.descriptor, g.descriptor)
+			if d < best {
+				second = best
+				best, bestIndex = d, i
+			} else if d < second {
+				second = d
+			}
+		}
+		if float64(best) < o.FeatureRatio*float64(second) {
+			g := backgroundFeatures[bestIndex]
+			matches = append(matches, FeatureMatch{
+				SrcX: float64(f.x), SrcY: float64(f.y),
+				DstX: float64(g.x), DstY: float64(g.y),
+			})
+		}
+	}
+
+	bounds := target.Bounds()
+	return FeatureResult(matches, image.Pt(bounds.Dx(), bounds.Dy()), targetY, o, StrategyORB), nil
+}
+
+// 检测关键点并计算描述子，关键点按FAST得分降序排列
+func orbFeatures(ctx context.Context, img *image.Gray, mask *image.Alpha, workers int) ([]orbFeature, error) {
+	img = cropGray(img, img.Bounds())
+	width, height := img.Bounds().Dx(), img.Bounds().Dy()
+	border := orbPatchRadius + 1
+	if width <= 2*border || height <= 2*border {
+		return nil, nil
+	}
+
+	// FAST角点得分，非角点为0
+	scores := make([]float64, width*height)
+	err := parallelRows(ctx, border, height-border, workers, func(y int) {
+		for x := border; x < width-border; x++ {
+			if mask != nil && !patchOpaque(mask, x, y) {
+				continue
+			}
+			scores[y*width+x] = fastScore(img, x, y, orbFastThreshold)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 3x3非极大值抑制，得分相同时保留先扫描到的点
+	features := make([]orbFeature, 0)
+	for y := border; y < height-border; y++ {
+		for x := border; x < width-border; x++ {
+			score := scores[y*width+x]
+			if score == 0 {
+				continue
+			}
+			local := true
+			for dy := -1; dy <= 1 && local; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					neighbor := scores[(y+dy)*width+x+dx]
+					before := dy < 0 || (dy == 0 && dx < 0)
+					if (before && neighbor >= score) || (!before && neighbor > score) {
+						local = false
+						break
+					}
+				}
+			}
+			if local {
+				features = append(features, orbFeature{x: x, y: y, score: score})
+			}
+		}
+	}
+	sort.SliceStable(features, func(i, j int) bool {
+		return features[i].score > features[j].score
+	})
+	features = features[:min(len(features), orbMaxKeypoints)]
+
+	// 描述子在平滑后的图像上计算，降低噪声的影响
+	smoothed, err := gaussianBlur(ctx, img, workers)
+	if err != nil {
+		return nil, err
+	}
+	for i := range features {
+		angle := patchOrientation(img, features[i].x, features[i].y)
+		features[i].descriptor = briefDescriptor(smoothed, features[i].x, features[i].y, angle)
+	}
+
+	return features, nil
+}
+
+// FAST-9角点得分：圆周上存在至少9个连续像素均比中心亮或暗threshold以上时，
+// 得分为超出阈值部分之和，否则为0
+func fastScore(img *image.Gray, x, y int, threshold int) float64 {
+	center := int(img.Pix[img.PixOffset(x, y)])
+
+	var signs [16]int
+	var sum int
+	for i, p := range fastCircle {
+		diff := int(img.Pix[img.PixOffset(x+p.X, y+p.Y)]) - center
+		switch {
+		case diff > threshold:
+			signs[i] = 1
+			sum += diff - threshold
+		case diff < -threshold:
+			signs[i] = -1
+			sum += -diff - threshold
+		}
+	}
+
+	// 环形遍历两圈寻找连续段
+	run := 0
+	for i := range 32 {
+		s := signs[i%16]
+		if s != 0 && i > 0 && s == signs[(i-1)%16] {
+			run++
+		} else if s != 0 {
+			run = 1
+		} else {
+			run = 0
+		}
+		if run >= 9 {
+			return float64(sum)
+		}
+	}
+	return 0
+}
+
+// 以(x, y)为中心的采样区域内掩码是否完全不透明
+func patchOpaque(mask *image.Alpha, x, y int) bool {
+	bounds := mask.Bounds()
+	for dy := -orbPatchRadius; dy <= orbPatchRadius; dy++ {
+		for dx := -orbPatchRadius; dx <= orbPatchRadius; dx++ {
+			if dx*dx+dy*dy > orbPatchRadius*orbPatchRadius {
+				continue
+			}
+			if mask.AlphaAt(bounds.Min.X+x+dx, bounds.Min.Y+y+dy).A == 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// 灰度质心法计算关键点方向
+func patchOrientation(img *image.Gray, x, y int) float64 {
+	var m01, m10 float64
+	for dy := -orbPatchRadius; dy <= orbPatchRadius; dy++ {
+		for dx := -orbPatchRadius; dx <= orbPatchRadius; dx++ {
+			if dx*dx+dy*dy > orbPatchRadius*orbPatchRadius {
+				continue
+			}
+			v := float64(img.Pix[img.PixOffset(x+dx, y+dy)])
+			m10 += float64(dx) * v
+			m01 += float64(dy) * v
+		}
+	}
+	return math.Atan2(m01, m10)
+}
+
+// 按关键点方向旋转采样点后比较灰度，得到256位描述子
+func briefDescriptor(img *image.Gray, x, y int, angle float64) [4]uint64 {
+	sin, cos := math.Sincos(angle)
+	at := func(p image.Point) uint8 {
+		rx := int(math.Round(cos*float64(p.X) - sin*float64(p.Y)))
+		ry := int(math.Round(sin*float64(p.X) + cos*float64(p.Y)))
+		return img.Pix[img.PixOffset(x+rx, y+ry)]
+	}
+
+	var descriptor [4]uint64
+	for i, pair := range orbPattern {
+		if at(pair[0]) < at(pair[1]) {
+			descriptor[i/64] |= 1 << (i % 64)
+		}
+	}
+	return descriptor
+}
+
+// 描述子的汉明距离
+func hammingDistance(a, b [4]uint64) int {
+	distance := 0
+	for i := range a {
+		distance += bits.OnesCount64(a[i] ^ b[i])
+	}
+	return distance
+}
@@ -0,0 +1,52 @@
+package ddddgocr
+
+import (
+	"context"
+	"sync"
+)
+
+// 将[start, end)的行划分为连续行带，由workers个协程分别对每行执行fn，
+// 各行写入互不重叠时结果与顺序执行一致；workers不大于1时在当前协程顺序执行
+func parallelRows(ctx context.Context, start, end, workers int, fn func(y int)) error {
+	rows := end - start
+	if workers > rows {
+		workers = rows
+	}
+
+	if workers <= 1 {
+		for y := start; y < end; y++ {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			fn(y)
+		}
+		return nil
+	}
+
+	band := (rows + workers - 1) / workers
+	errs := make([]error, workers)
+	var wg sync.WaitGroup
+	for i := range workers {
+		bandStart := start + i*band
+		bandEnd := min(bandStart+band, end)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for y := bandStart; y < bandEnd; y++ {
+				if err := ctx.Err(); err != nil {
+					errs[i] = err
+					return
+				}
+				fn(y)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
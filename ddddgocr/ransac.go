@@ -0,0 +1,223 @@
+package ddddgocr
+
+import (
+	"image"
+	"math"
+	"math/rand/v2"
+)
+
+// FeatureMatch 一对通过比值检验的特征点匹配
+type FeatureMatch struct {
+	SrcX, SrcY float64 // 滑块中的坐标
+	DstX, DstY float64 // 背景中的坐标
+}
+
+// Transform 滑块到背景的相似变换：dst = Scale·R(Angle)·src + (TX, TY)，
+// 只估计平移时Scale为1、Angle为0
+type Transform struct {
+	TX, TY float64
+	Scale  float64
+	Angle  float64 // 旋转角度（弧度）
+}
+
+// Apply 将滑块中的点变换到背景
+func (t Transform) Apply(x, y float64) (float64, float64) {
+	sin, cos := math.Sincos(t.Angle)
+	return t.Scale*(cos*x-sin*y) + t.TX, t.Scale*(sin*x+cos*y) + t.TY
+}
+
+// 相似变换随机采样的最大假设数，点对组合数不超过该值时穷举
+const ransacIterations = 1000
+
+// EstimateTransform 以RANSAC从匹配点对估计滑块到背景的变换，similarity为false时只估计平移；
+// 重投影误差不超过tolerance的点对为内点，返回以全部内点最小二乘拟合的变换与内点下标
+func EstimateTransform(matches []FeatureMatch, similarity bool, tolerance float64) (Transform, []int) {
+	if len(matches) == 0 {
+		return Transform{Scale: 1}, nil
+	}
+
+	fit := fitTranslation
+	if similarity {
+		fit = fitSimilarity
+	}
+
+	var best []int
+	var bestError float64
+	try := func(sample []int) {
+		t, ok := fit(matches, sample)
+		if !ok {
+			return
+		}
+		inliers, err := transformInliers(matches, t, tolerance)
+		if len(inliers) > len(best) || (len(inliers) == len(best) && err < bestError) {
+			best, bestError = inliers, err
+		}
+	}
+
+	// 平移由单个点对确定，逐个尝试；相似变换由两个点对确定
+	n := len(matches)
+	switch {
+	case !similarity:
+		for i := range n {
+			try([]int{i})
+		}
+	case n*(n-1)/2 <= ransacIterations:
+		for i := range n {
+			for j := i + 1; j < n; j++ {
+				try([]int{i, j})
+			}
+		}
+	default:
+		// 固定种子，相同输入得到相同结果
+		rng := rand.New(rand.NewPCG(uint64(n), 0))
+		for range ransacIterations {
+			i := rng.IntN(n)
+			j := rng.IntN(n - 1)
+			if j >= i {
+				j++
+			}
+			try([]int{i, j})
+		}
+	}
+
+	if len(best) == 0 {
+		return Transform{Scale: 1}, nil
+	}
+
+	// 以内点重新拟合，内点集合不再变化为止
+	for range 5 {
+		t, ok := fit(matches, best)
+		if !ok {
+			break
+		}
+		inliers, _ := transformInliers(matches, t, tolerance)
+		if len(inliers) < len(best) || sameIndices(inliers, best) {
+			break
+		}
+		best = inliers
+	}
+
+	t, _ := fit(matches, best)
+	return t, best
+}
+
+// 变换t下的内点下标与内点重投影误差平方和
+func transformInliers(matches []FeatureMatch, t Transform, tolerance float64) ([]int, float64) {
+	inliers := make([]int, 0)
+	var total float64
+	for i, m := range matches {
+		x, y := t.Apply(m.SrcX, m.SrcY)
+		dx, dy := x-m.DstX, y-m.DstY
+		if e := dx*dx + dy*dy; e <= tolerance*tolerance {
+			inliers = append(inliers, i)
+			total += e
+		}
+	}
+	return inliers, total
+}
+
+// 最小二乘拟合平移，即偏移量的均值
+func fitTranslation(matches []FeatureMatch, indices []int) (Transform, bool) {
+	if len(indices) == 0 {
+		return Transform{}, false
+	}
+	var tx, ty float64
+	for _, i := range indices {
+		tx += matches[i].DstX - matches[i].SrcX
+		ty += matches[i].DstY - matches[i].SrcY
+	}
+	n := float64(len(indices))
+	return Transform{TX: tx / n, TY: ty / n, Scale: 1}, true
+}
+
+// 最小二乘拟合相似变换，滑块中的点过于集中时无法确定旋转与缩放
+func fitSimilarity(matches []FeatureMatch, indices []int) (Transform, bool) {
+	if len(indices) < 2 {
+		return Transform{}, false
+	}
+
+	var srcX, srcY, dstX, dstY float64
+	for _, i := range indices {
+		srcX += matches[i].SrcX
+		srcY += matches[i].SrcY
+		dstX += matches[i].DstX
+		dstY += matches[i].DstY
+	}
+	n := float64(len(indices))
+	srcX, srcY, dstX, dstY = srcX/n, srcY/n, dstX/n, dstY/n
+
+	// 以重心为原点，[a -b; b a]为缩放与旋转
+	var a, b, norm float64
+	for _, i := range indices {
+		sx, sy := matches[i].SrcX-srcX, matches[i].SrcY-srcY
+		dx, dy := matches[i].DstX-dstX, matches[i].DstY-dstY
+		a += sx*dx + sy*dy
+		b += sx*dy - sy*dx
+		norm += sx*sx + sy*sy
+	}
+	if norm < 1 {
+		return Transform{}, false
+	}
+	a /= norm
+	b /= norm
+
+	t := Transform{Scale: math.Hypot(a, b), Angle: math.Atan2(b, a)}
+	if t.Scale == 0 {
+		return Transform{}, false
+	}
+	x, y := t.Apply(srcX, srcY)
+	t.TX, t.TY = dstX-x, dstY-y
+	return t, true
+}
+
+// 两组升序下标是否相同
+func sameIndices(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// FeatureResult 由通过比值检验的匹配点对估计滑块位置，size为滑块尺寸，targetY为滑块在目标图像中的Y；
+// 得分为RANSAC内点占比，内点少于FeatureMinInliers时返回nil
+func FeatureResult(matches []FeatureMatch, size image.Point, targetY int, o *Options, strategy Strategy) *SlideResult {
+	minInliers := max(o.FeatureMinInliers, 1)
+	if len(matches) < minInliers {
+		return nil
+	}
+
+	t, inliers := EstimateTransform(matches, o.FeatureSimilarity, o.FeatureTolerance)
+	if len(inliers) < minInliers {
+		return nil
+	}
+
+	x1, y1 := int(math.Round(t.TX)), int(math.Round(t.TY))
+	candidate := SlideCandidate{
+		SlideBBox: SlideBBox{
+			TargetY: targetY,
+			X1:      x1,
+			Y1:      y1,
+			X2:      x1 + scaleLength(size.X, t.Scale),
+			Y2:      y1 + scaleLength(size.Y, t.Scale),
+			SubX:    t.TX,
+			SubY:    t.TY,
+		},
+		Score:    float64(len(inliers)) / float64(len(matches)),
+		Strategy: strategy,
+	}
+
+	// 只有一个候选，Margin与Score相同
+	return &SlideResult{
+		SlideBBox:  candidate.SlideBBox,
+		Score:      candidate.Score,
+		Margin:     candidate.Score,
+		Strategy:   strategy,
+		Scale:      t.Scale,
+		Candidates: []SlideCandidate{candidate},
+	}
+}
@@ -0,0 +1,125 @@
+package ddddgocr
+
+import (
+	"context"
+	"image"
+)
+
+// 连通区域，labels中编号为id的像素属于该区域
+type pixelRegion struct {
+	id     int32
+	labels []int32
+	bounds image.Rectangle
+	pixels []int
+}
+
+// 标记满足in的连通区域，eight为true时按8连通，否则按4连通
+func labelRegions(ctx context.Context, width, height int, eight bool, in func(i int) bool) ([]pixelRegion, error) {
+	labels := make([]int32, width*height)
+	regions := make([]pixelRegion, 0)
+	stack := make([]int, 0)
+
+	for y := range height {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		for x := range width {
+			start := y*width + x
+			if labels[start] != 0 || !in(start) {
+				continue
+			}
+
+			region := pixelRegion{id: int32(len(regions) + 1), labels: labels, bounds: image.Rect(x, y, x+1, y+1)}
+			labels[start] = region.id
+			stack = append(stack[:0], start)
+			for len(stack) > 0 {
+				i := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				region.pixels = append(region.pixels, i)
+
+				px, py := i%width, i/width
+				region.bounds = region.bounds.Union(image.Rect(px, py, px+1, py+1))
+				for dy := -1; dy <= 1; dy++ {
+					for dx := -1; dx <= 1; dx++ {
+						if (dx == 0 && dy == 0) || (!eight && dx != 0 && dy != 0) {
+							continue
+						}
+						nx, ny := px+dx, py+dy
+						if nx < 0 || nx >= width || ny < 0 || ny >= height {
+							continue
+						}
+						if j := ny*width + nx; labels[j] == 0 && in(j) {
+							labels[j] = region.id
+							stack = append(stack, j)
+						}
+					}
+				}
+			}
+			regions = append(regions, region)
+		}
+	}
+
+	return regions, nil
+}
+
+// 两个边界框的交并比
+func boxIoU(a, b SlideBBox) float64 {
+	ra := image.Rect(a.X1, a.Y1, a.X2, a.Y2)
+	rb := image.Rect(b.X1, b.Y1, b.X2, b.Y2)
+	inter := ra.Intersect(rb)
+	if inter.Empty() {
+		return 0
+	}
+	interArea := inter.Dx() * inter.Dy()
+	return float64(interArea) / float64(ra.Dx()*ra.Dy()+rb.Dx()*rb.Dy()-interArea)
+}
+
+// 二值图的开运算（先腐蚀后膨胀），size为方形结构元素边长，可去除窄于size的零散像素；
+// 越界的邻域不参与计算，与OpenCV的默认边界处理一致
+func morphOpen(ctx context.Context, img *image.Gray, size, workers int) (*image.Gray, error) {
+	img = cropGray(img, img.Bounds())
+	for _, pass := range []struct{ erode, horizontal bool }{
+		{true, true}, {true, false}, {false, true}, {false, false},
+	} {
+		var err error
+		if img, err = morphPass(ctx, img, size, pass.erode, pass.horizontal, workers); err != nil {
+			return nil, err
+		}
+	}
+	return img, nil
+}
+
+// 方形结构元素可分解为横、纵两次一维腐蚀或膨胀，锚点位于结构元素中心
+func morphPass(ctx context.Context, img *image.Gray, size int, erode, horizontal bool, workers int) (*image.Gray, error) {
+	width, height := img.Bounds().Dx(), img.Bounds().Dy()
+	result := image.NewGray(img.Bounds())
+
+	err := parallelRows(ctx, 0, height, workers, func(y int) {
+		for x := range width {
+			// 腐蚀时窗口内全部为前景才保留，膨胀时存在前景即置位
+			set := erode
+			for k := -size / 2; k < size-size/2; k++ {
+				nx, ny := x, y
+				if horizontal {
+					nx += k
+				} else {
+					ny += k
+				}
+				if nx < 0 || nx >= width || ny < 0 || ny >= height {
+					continue
+				}
+				if foreground := img.Pix[ny*img.Stride+nx] != 0; foreground != erode {
+					set = foreground
+					break
+				}
+			}
+			if set {
+				result.Pix[y*result.Stride+x] = 255
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,224 @@
+package ddddgocr
+
+import (
+	"context"
+	"image"
+	"math"
+	"time"
+)
+
+const (
+	rotateSteps      = 360 // 角度采样数，即1°的分辨率
+	rotateEdgeMargin = 2   // 内圈边界两侧跳过的像素数，避开裁切处的锯齿与描边
+	rotateSeparation = 10  // 次佳角度与最佳角度至少相差的度数
+)
+
+// RotateResult 旋转验证码的匹配结果
+type RotateResult struct {
+	Angle    float64       // 将内圈（或旋转后的图片）顺时针旋转该角度即可摆正，范围[0, 360)
+	Distance float64       // 对应的滑块移动距离，未设置RotateTrackLength时为0
+	Score    float64       // 摆正后边界两侧（或与参考图）的标准化相关系数
+	Margin   float64       // 最佳得分与相距至少10°的次佳得分之差
+	Ring     bool          // 是否按内圈与外环的边界连续性匹配，否则与参考图整体匹配
+	Engine   string        // 执行匹配的引擎
+	Elapsed  time.Duration // 匹配耗时
+}
+
+// RotateDistance 将顺时针旋转角度换算为滑块移动距离，fullAngle为滑块移动完整轨道时的旋转角度
+func RotateDistance(angle, trackLength, fullAngle float64) float64 {
+	if fullAngle <= 0 {
+		return 0
+	}
+	return angle / fullAngle * trackLength
+}
+
+// RotateMatch 旋转验证码匹配，inner为内圈圆盘（或旋转后的图片），outer为外环（或摆正的参考图）
+func RotateMatch(innerImageData, outerImageData []byte, opts ...Option) (*RotateResult, error) {
+	return RotateMatchContext(context.Background(), innerImageData, outerImageData, opts...)
+}
+
+// RotateMatchContext 可取消的旋转验证码匹配
+func RotateMatchContext(ctx context.Context, innerImageData, outerImageData []byte, opts ...Option) (*RotateResult, error) {
+	start := time.Now()
+
+	// 解码图像，内圈为目标图像，外环为背景图像
+	innerImg, outerImg, err := decodeImages(innerImageData, outerImageData)
+	if err != nil {
+		return nil, err
+	}
+
+	return rotateMatch(ctx, start, innerImg, outerImg, NewOptions(opts...))
+}
+
+// RotateMatchImage 对已解码图像进行旋转验证码匹配
+func RotateMatchImage(ctx context.Context, innerImg, outerImg image.Image, opts ...Option) (*RotateResult, error) {
+	return rotateMatch(ctx, time.Now(), originImage(innerImg), originImage(outerImg), NewOptions(opts...))
+}
+
+// 极坐标采样的环带，values[k][t]为第k个半径、第t个角度处的值，valid标记采样点是否有效
+type polarBand struct {
+	values [][]float64
+	valid  [][]bool
+}
+
+// 旋转验证码匹配流程，start为计时起点：
+// 外环明显大于内圈时比较内圈边界内侧与外环边界外侧的环带，否则在整个圆盘内与参考图比较；
+// 灰度与Canny边缘分别在各旋转角度下计算标准化相关，取两者平均值最大的角度
+func rotateMatch(ctx context.Context, start time.Time, innerImg, outerImg image.Image, o *Options) (*RotateResult, error) {
+	// 内圈裁剪透明区域，圆心为裁剪后的中心
+	inner, _, _ := cropTransparent(toRGBA(innerImg))
+	innerGray := rgbaToGrayScale(inner)
+	innerMask := alphaMask(inner)
+	innerSize := inner.Bounds().Size()
+
+	outer := toRGBA(outerImg)
+	outerGray := rgbaToGrayScale(outer)
+	outerMask := alphaMask(outer)
+	outerSize := outer.Bounds().Size()
+
+	radius := float64(min(innerSize.X, innerSize.Y)) / 2
+	outerRadius := float64(min(outerSize.X, outerSize.Y)) / 2
+	if radius < 2*rotateEdgeMargin+2 {
+		return nil, &SizeMismatchError{Target: innerSize, Background: outerSize}
+	}
+
+	// 环带宽度取半径的1/8，参考图模式取半径的20%~90%
+	band := max(4, radius/8)
+	ring := outerRadius >= radius+rotateEdgeMargin+band
+	var innerRadii, outerRadii []float64
+	if ring {
+		for r := rotateEdgeMargin; float64(r) < band; r++ {
+			innerRadii = append(innerRadii, radius-float64(r))
+			outerRadii = append(outerRadii, radius+float64(r))
+		}
+	} else {
+		for r := 0.2 * radius; r <= 0.9*radius; r++ {
+			innerRadii = append(innerRadii, r)
+			outerRadii = append(outerRadii, r*outerRadius/radius)
+		}
+	}
+
+	innerEdges, err := cannyEdgeDetection(ctx, innerGray, o.CannyLow.Low, o.CannyLow.High, o.workerCount())
+	if err != nil {
+		return nil, err
+	}
+	outerEdges, err := cannyEdgeDetection(ctx, outerGray, o.CannyLow.Low, o.CannyLow.High, o.workerCount())
+	if err != nil {
+		return nil, err
+	}
+
+	innerCenter := [2]float64{float64(innerSize.X) / 2, float64(innerSize.Y) / 2}
+	outerCenter := [2]float64{float64(outerSize.X) / 2, float64(outerSize.Y) / 2}
+	innerBand := samplePolar(innerGray, innerMask, innerCenter, innerRadii)
+	outerBand := samplePolar(outerGray, outerMask, outerCenter, outerRadii)
+	innerEdgeBand := samplePolar(innerEdges, innerMask, innerCenter, innerRadii)
+	outerEdgeBand := samplePolar(outerEdges, outerMask, outerCenter, outerRadii)
+
+	// 内圈旋转shift个采样角度后与外环比较
+	scores := make([]float64, rotateSteps)
+	err = parallelRows(ctx, 0, rotateSteps, o.workerCount(), func(shift int) {
+		scores[shift] = (bandCorrelation(innerBand, outerBand, shift) + bandCorrelation(innerEdgeBand, outerEdgeBand, shift)) / 2
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	best := 0
+	for shift, score := range scores {
+		if score > scores[best] {
+			best = shift
+		}
+	}
+	second := math.Inf(-1)
+	for shift, score := range scores {
+		if d := min(abs(shift-best), rotateSteps-abs(shift-best)); d >= rotateSeparation*rotateSteps/360 && score > second {
+			second = score
+		}
+	}
+
+	// 抛物线拟合细化到1°以下
+	offset := parabolaOffset(scores[(best+rotateSteps-1)%rotateSteps], scores[best], scores[(best+1)%rotateSteps])
+	shiftAngle := (float64(best) + offset) * 360 / rotateSteps
+
+	// 内圈内容相对摆正位置顺时针转过了shiftAngle，需再顺时针旋转其补角
+	angle := math.Mod(360-shiftAngle, 360)
+	if angle < 0 {
+		angle += 360
+	}
+
+	result := &RotateResult{
+		Angle:    angle,
+		Distance: RotateDistance(angle, o.RotateTrackLength, o.RotateFullAngle),
+		Score:    scores[best],
+		Margin:   scores[best] - second,
+		Ring:     ring,
+		Engine:   EngineName,
+		Elapsed:  time.Since(start),
+	}
+	if math.IsInf(second, -1) {
+		result.Margin = result.Score
+	}
+	return result, nil
+}
+
+// 以center为圆心在各半径上按rotateSteps个角度双线性采样，角度0指向右侧、顺时针增大；
+// 超出图像或落在透明像素上的采样点无效
+func samplePolar(img *image.Gray, mask *image.Alpha, center [2]float64, radii []float64) polarBand {
+	bounds := img.Bounds()
+	band := polarBand{values: make([][]float64, len(radii)), valid: make([][]bool, len(radii))}
+
+	for k, r := range radii {
+		band.values[k] = make([]float64, rotateSteps)
+		band.valid[k] = make([]bool, rotateSteps)
+		for t := range rotateSteps {
+			sin, cos := math.Sincos(2 * math.Pi * float64(t) / rotateSteps)
+			// 像素中心位于整数坐标+0.5
+			x := center[0] + r*cos - 0.5
+			y := center[1] + r*sin - 0.5
+			x0, y0 := int(math.Floor(x)), int(math.Floor(y))
+			if x0 < 0 || y0 < 0 || x0+1 >= bounds.Dx() || y0+1 >= bounds.Dy() {
+				continue
+			}
+			if mask != nil && mask.AlphaAt(int(math.Round(x)), int(math.Round(y))).A == 0 {
+				continue
+			}
+			fx, fy := x-float64(x0), y-float64(y0)
+			top := getGrayValue(img, bounds.Min.X+x0, bounds.Min.Y+y0)*(1-fx) + getGrayValue(img, bounds.Min.X+x0+1, bounds.Min.Y+y0)*fx
+			bottom := getGrayValue(img, bounds.Min.X+x0, bounds.Min.Y+y0+1)*(1-fx) + getGrayValue(img, bounds.Min.X+x0+1, bounds.Min.Y+y0+1)*fx
+			band.values[k][t] = top*(1-fy) + bottom*fy
+			band.valid[k][t] = true
+		}
+	}
+	return band
+}
+
+// 内圈环带旋转shift个采样角度后与外环环带的标准化相关系数，只统计两侧均有效的采样点，
+// 任一侧方差为零时为0
+func bandCorrelation(inner, outer polarBand, shift int) float64 {
+	var n, sumA, sumB, sumAA, sumBB, sumAB float64
+	for k := range inner.values {
+		for t := range rotateSteps {
+			s := (t + shift) % rotateSteps
+			if !inner.valid[k][s] || !outer.valid[k][t] {
+				continue
+			}
+			a, b := inner.values[k][s], outer.values[k][t]
+			n++
+			sumA += a
+			sumB += b
+			sumAA += a * a
+			sumBB += b * b
+			sumAB += a * b
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+
+	varA := sumAA - sumA*sumA/n
+	varB := sumBB - sumB*sumB/n
+	if varA <= 1e-9 || varB <= 1e-9 {
+		return 0
+	}
+	return (sumAB - sumA*sumB/n) / math.Sqrt(varA*varB)
+}
@@ -0,0 +1,84 @@
+package ddddgocr
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// 平滑的测试纹理，(x, y)相对圆心，各角度互不相同且在内圈边界两侧连续
+func rotateTexture(x, y float64) uint8 {
+	return uint8(128 + 50*math.Sin(x/9+y/17) + 40*math.Cos(y/7-x/23))
+}
+
+// 边长为size的图像，圆心在中心，内容顺时针转过tilt度；radius大于0时只保留该半径内的圆盘，其余透明
+func rotateDisc(size int, radius, tilt float64) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	c := float64(size) / 2
+	sin, cos := math.Sincos(tilt * math.Pi / 180)
+	for y := range size {
+		for x := range size {
+			dx, dy := float64(x)+0.5-c, float64(y)+0.5-c
+			if radius > 0 && math.Hypot(dx, dy) > radius {
+				continue
+			}
+			// 逆时针转回tilt度取摆正时的纹理
+			v := rotateTexture(cos*dx+sin*dy, -sin*dx+cos*dy)
+			img.SetRGBA(x, y, color.RGBA{R: v, G: v, B: v, A: 255})
+		}
+	}
+	return img
+}
+
+func TestRotateMatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		outer *image.RGBA
+		ring  bool
+	}{
+		// 外环明显大于内圈，比较边界两侧
+		{"ring", rotateDisc(180, 0, 0), true},
+		// 与摆正的参考图整体比较
+		{"reference", rotateDisc(100, 50, 0), false},
+	}
+	for _, tt := range tests {
+		for _, tilt := range []float64{37, 152.5, 290} {
+			inner := rotateDisc(100, 50, tilt)
+			result, err := RotateMatchImage(context.Background(), inner, tt.outer, WithRotateTrack(280, 360))
+			if err != nil {
+				t.Fatalf("%s %v°: %v", tt.name, tilt, err)
+			}
+			want := 360 - tilt
+			if d := math.Abs(math.Mod(result.Angle-want+540, 360) - 180); d > 1 {
+				t.Errorf("%s %v°: Angle = %.2f, want %.2f", tt.name, tilt, result.Angle, want)
+			}
+			if math.Abs(result.Distance-result.Angle/360*280) > 1e-9 {
+				t.Errorf("%s %v°: Distance = %v for Angle %v", tt.name, tilt, result.Distance, result.Angle)
+			}
+			if result.Ring != tt.ring {
+				t.Errorf("%s %v°: Ring = %v, want %v", tt.name, tilt, result.Ring, tt.ring)
+			}
+			if result.Margin <= 0 {
+				t.Errorf("%s %v°: Margin = %v", tt.name, tilt, result.Margin)
+			}
+		}
+	}
+}
+
+func TestRotateDistance(t *testing.T) {
+	tests := []struct {
+		angle, track, full, want float64
+	}{
+		{90, 280, 360, 70},
+		{90, 200, 180, 100},
+		{0, 280, 360, 0},
+		{90, 280, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := RotateDistance(tt.angle, tt.track, tt.full); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("RotateDistance(%v, %v, %v) = %v, want %v", tt.angle, tt.track, tt.full, got, tt.want)
+		}
+	}
+}
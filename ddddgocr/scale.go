@@ -0,0 +1,276 @@
+package ddddgocr
+
+import (
+	"image"
+	"math"
+)
+
+// SearchScales 由粗到细搜索滑块的缩放倍数：先以ScaleStep遍历[ScaleMin, ScaleMax]，
+// 再在最佳倍数两侧一个步长内以ScaleStep/4细化，返回得分最高的结果并记录其Scale；
+// match返回nil表示该倍数下无法匹配（如缩放后大于背景），未设置尺度范围时只匹配原始尺寸
+func SearchScales(o *Options, match func(scale float64) (*SlideResult, error)) (*SlideResult, error) {
+	var best *SlideResult
+	try := func(scale float64) error {
+		result, err := match(scale)
+		if err != nil {
+			return err
+		}
+		if result != nil && (best == nil || result.Score > best.Score) {
+			result.Scale = scale
+			best = result
+		}
+		return nil
+	}
+
+	minScale, maxScale, step := o.scaleRange()
+	if step == 0 {
+		if err := try(1); err != nil {
+			return nil, err
+		}
+		return best, nil
+	}
+
+	// 粗搜索，按步数计算避免浮点累加误差
+	steps := int(math.Floor((maxScale-minScale)/step + 1e-9))
+	for i := 0; i <= steps; i++ {
+		if err := try(minScale + float64(i)*step); err != nil {
+			return nil, err
+		}
+	}
+	if last := minScale + float64(steps)*step; maxScale-last > 1e-9 {
+		if err := try(maxScale); err != nil {
+			return nil, err
+		}
+	}
+	if best == nil {
+		return nil, nil
+	}
+
+	// 细搜索
+	center := best.Scale
+	for i := -3; i <= 3; i++ {
+		scale := center + float64(i)*step/4
+		if i == 0 || scale < minScale-1e-9 || scale > maxScale+1e-9 {
+			continue
+		}
+		if err := try(scale); err != nil {
+			return nil, err
+		}
+	}
+
+	return best, nil
+}
+
+// 有效的尺度搜索范围，未启用时step为0
+func (o *Options) scaleRange() (minScale, maxScale, step float64) {
+	minScale, maxScale = o.ScaleMin, o.ScaleMax
+	if minScale > maxScale {
+		minScale, maxScale = maxScale, minScale
+	}
+	if minScale <= 0 || o.ScaleStep <= 0 || (minScale == 1 && maxScale == 1) {
+		return 1, 1, 0
+	}
+	return minScale, maxScale, o.ScaleStep
+}
+
+// 按倍数缩放的整数长度，至少为1
+func scaleLength(length int, scale float64) int {
+	return max(1, int(math.Round(float64(length)*scale)))
+}
+
+// 按倍数缩放滑块灰度图与掩码，灰度图双线性插值，掩码取最近邻；倍数为1时原样返回
+func scalePiece(gray *image.Gray, mask *image.Alpha, scale float64) (*image.Gray, *image.Alpha) {
+	if scale == 1 {
+		return gray, mask
+	}
+
+	bounds := gray.Bounds()
+	width := scaleLength(bounds.Dx(), scale)
+	height := scaleLength(bounds.Dy(), scale)
+	scaleX := float64(bounds.Dx()) / float64(width)
+	scaleY := float64(bounds.Dy()) / float64(height)
+
+	scaled := image.NewGray(image.Rect(0, 0, width, height))
+	for y := range height {
+		// 像素中心对齐
+		sy := math.Max(0, (float64(y)+0.5)*scaleY-0.5)
+		y0 := min(int(sy), bounds.Dy()-1)
+		y1 := min(y0+1, bounds.Dy()-1)
+		fy := sy - float64(y0)
+		for x := range width {
+			sx := math.Max(0, (float64(x)+0.5)*scaleX-0.5)
+			x0 := min(int(sx), bounds.Dx()-1)
+			x1 := min(x0+1, bounds.Dx()-1)
+			fx := sx - float64(x0)
+
+			top := getGrayValue(gray, bounds.Min.X+x0, bounds.Min.Y+y0)*(1-fx) + getGrayValue(gray, bounds.Min.X+x1, bounds.Min.Y+y0)*fx
+			bottom := getGrayValue(gray, bounds.Min.X+x0, bounds.Min.Y+y1)*(1-fx) + getGrayValue(gray, bounds.Min.X+x1, bounds.Min.Y+y1)*fx
+			scaled.Pix[y*scaled.Stride+x] = uint8(math.Round(top*(1-fy) + bottom*fy))
+		}
+	}
+
+	if mask == nil {
+		return scaled, nil
+	}
+
+	maskBounds := mask.Bounds()
+	scaledMask := image.NewAlpha(image.Rect(0, 0, width, height))
+	for y := range height {
+		sy := min(int((float64(y)+0.5)*scaleY), maskBounds.Dy()-1)
+		for x := range width {
+			sx := min(int((float64(x)+0.5)*scaleX), maskBounds.Dx()-1)
+			scaledMask.Pix[y*scaledMask.Stride+x] = mask.AlphaAt(maskBounds.Min.X+sx, maskBounds.Min.Y+sy).A
+		}
+	}
+
+	return scaled, scaledMask
+}
+
+// 按角度（度，顺时针）绕中心旋转滑块灰度图与掩码，画布扩大到能容纳旋转结果，
+// 灰度图双线性插值，掩码取最近邻，原图以外的区域掩码为0；mask为nil时视为完全不透明
+func rotatePiece(gray *image.Gray, mask *image.Alpha, degrees float64) (*image.Gray, *image.Alpha) {
+	bounds := gray.Bounds()
+	srcW, srcH := float64(bounds.Dx()), float64(bounds.Dy())
+	sin, cos := math.Sincos(degrees * math.Pi / 180)
+	width := int(math.Ceil(math.Abs(srcW*cos) + math.Abs(srcH*sin) - 1e-9))
+	height := int(math.Ceil(math.Abs(srcW*sin) + math.Abs(srcH*cos) - 1e-9))
+
+	rotated := image.NewGray(image.Rect(0, 0, width, height))
+	rotatedMask := image.NewAlpha(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			// 目标像素中心逆旋转回原图
+			dx, dy := float64(x)+0.5-float64(width)/2, float64(y)+0.5-float64(height)/2
+			sx := cos*dx + sin*dy + srcW/2 - 0.5
+			sy := -sin*dx + cos*dy + srcH/2 - 0.5
+			nx, ny := int(math.Round(sx)), int(math.Round(sy))
+			if nx < 0 || ny < 0 || nx >= bounds.Dx() || ny >= bounds.Dy() {
+				continue
+			}
+			if mask != nil && mask.AlphaAt(mask.Bounds().Min.X+nx, mask.Bounds().Min.Y+ny).A == 0 {
+				continue
+			}
+			rotatedMask.Pix[y*rotatedMask.Stride+x] = 255
+
+			x0 := min(max(int(math.Floor(sx)), 0), bounds.Dx()-1)
+			y0 := min(max(int(math.Floor(sy)), 0), bounds.Dy()-1)
+			x1, y1 := min(x0+1, bounds.Dx()-1), min(y0+1, bounds.Dy()-1)
+			fx, fy := math.Max(0, math.Min(1, sx-float64(x0))), math.Max(0, math.Min(1, sy-float64(y0)))
+			top := getGrayValue(gray, bounds.Min.X+x0, bounds.Min.Y+y0)*(1-fx) + getGrayValue(gray, bounds.Min.X+x1, bounds.Min.Y+y0)*fx
+			bottom := getGrayValue(gray, bounds.Min.X+x0, bounds.Min.Y+y1)*(1-fx) + getGrayValue(gray, bounds.Min.X+x1, bounds.Min.Y+y1)*fx
+			rotated.Pix[y*rotated.Stride+x] = uint8(math.Round(top*(1-fy) + bottom*fy))
+		}
+	}
+
+	return rotated, rotatedMask
+}
+
+// SearchAngles 由粗到细搜索滑块的旋转角度：先以AngleStep遍历[-AngleMax, AngleMax]，
+// 再在最佳角度两侧一个步长内以AngleStep/4细化，返回得分最高的结果并记录其Angle；
+// match返回nil表示该角度下无法匹配，未设置角度范围时只匹配原始角度
+func SearchAngles(o *Options, match func(angle float64) (*SlideResult, error)) (*SlideResult, error) {
+	var best *SlideResult
+	try := func(angle float64) error {
+		result, err := match(angle)
+		if err != nil {
+			return err
+		}
+		if result != nil && (best == nil || result.Score > best.Score) {
+			result.Angle = angle
+			best = result
+		}
+		return nil
+	}
+
+	maxAngle, step := o.angleRange()
+	if step == 0 {
+		if err := try(0); err != nil {
+			return nil, err
+		}
+		return best, nil
+	}
+
+	// 粗搜索从0向两侧展开，得分相同时偏向较小的角度
+	steps := int(math.Floor(maxAngle/step + 1e-9))
+	if err := try(0); err != nil {
+		return nil, err
+	}
+	for i := 1; i <= steps; i++ {
+		for _, angle := range []float64{float64(i) * step, -float64(i) * step} {
+			if err := try(angle); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if last := float64(steps) * step; maxAngle-last > 1e-9 {
+		for _, angle := range []float64{maxAngle, -maxAngle} {
+			if err := try(angle); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if best == nil {
+		return nil, nil
+	}
+
+	// 细搜索
+	center := best.Angle
+	for i := -3; i <= 3; i++ {
+		angle := center + float64(i)*step/4
+		if i == 0 || math.Abs(angle) > maxAngle+1e-9 {
+			continue
+		}
+		if err := try(angle); err != nil {
+			return nil, err
+		}
+	}
+
+	return best, nil
+}
+
+// 有效的角度搜索范围，未启用时step为0
+func (o *Options) angleRange() (maxAngle, step float64) {
+	maxAngle = math.Abs(o.AngleMax)
+	if maxAngle == 0 || o.AngleStep <= 0 {
+		return 0, 0
+	}
+	return maxAngle, math.Min(o.AngleStep, maxAngle)
+}
+
+// 旋转滑块后按旋转得到的掩码重新裁剪到不透明区域，offset为裁剪结果左上角相对旋转前左上角的偏移；
+// 角度为0时原样返回。mask应为滑块形状，为nil时视为完全不透明，此时返回的掩码为旋转后的矩形；
+// 掩码以外的像素为0，是否以返回的掩码参与相关计算由调用方决定
+func rotateCropped(gray *image.Gray, mask *image.Alpha, degrees float64) (*image.Gray, *image.Alpha, image.Point) {
+	if degrees == 0 {
+		return gray, mask, image.Point{}
+	}
+
+	rotated, rotatedMask := rotatePiece(gray, mask, degrees)
+
+	// 不透明区域
+	width, height := rotated.Bounds().Dx(), rotated.Bounds().Dy()
+	opaque := image.Rectangle{}
+	for y := range height {
+		for x := range width {
+			if rotatedMask.Pix[y*rotatedMask.Stride+x] != 0 {
+				opaque = opaque.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	if opaque.Empty() {
+		return rotated, rotatedMask, image.Point{}
+	}
+
+	croppedMask := image.NewAlpha(image.Rect(0, 0, opaque.Dx(), opaque.Dy()))
+	for y := range opaque.Dy() {
+		copy(croppedMask.Pix[y*croppedMask.Stride:y*croppedMask.Stride+opaque.Dx()], rotatedMask.Pix[rotatedMask.PixOffset(opaque.Min.X, opaque.Min.Y+y):])
+	}
+
+	// 旋转画布与原图中心重合
+	bounds := gray.Bounds()
+	offset := opaque.Min.Add(image.Pt(
+		int(math.Round(float64(bounds.Dx()-width)/2)),
+		int(math.Round(float64(bounds.Dy()-height)/2)),
+	))
+	return cropGray(rotated, opaque), croppedMask, offset
+}
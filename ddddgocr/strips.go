@@ -0,0 +1,252 @@
+package ddddgocr
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/draw"
+	"image/png"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// StripOffset 一个分块在CSS中的background-position，通常为负值，即分块在拼图中的位置取反
+type StripOffset struct {
+	X, Y int
+}
+
+// StripLayout 还原后分块的排列方式
+type StripLayout struct {
+	Width   int // 每个分块的宽度
+	Height  int // 每个分块的高度，不大于0时为拼图高度除以行数
+	Columns int // 每行的分块数，不大于0时全部分块排成一行
+}
+
+// 偏移列表外层对象中常见的字段名
+var stripListKeys = []string{"offsets", "positions", "position", "pos", "data", "list"}
+
+// ParseStripOffsets 解析JSON格式的分块偏移列表，支持以下常见形式：
+// [{"x":-157,"y":-58}, ...]（也接受left/top字段与带px的字符串值）、[[-157,-58], ...]、
+// ["-157px -58px", ...]、只有水平偏移的[-157, ...]，以及在offsets、positions等字段中包含上述列表的对象
+func ParseStripOffsets(data []byte) ([]StripOffset, error) {
+	var doc any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, &StripOffsetError{Index: -1, Value: err.Error()}
+	}
+
+	// 外层对象取已知字段，没有时取唯一的数组字段
+	if object, ok := doc.(map[string]any); ok {
+		doc = stripList(object)
+	}
+	list, ok := doc.([]any)
+	if !ok || len(list) == 0 {
+		return nil, &StripOffsetError{Index: -1, Value: strings.TrimSpace(string(data))}
+	}
+
+	offsets := make([]StripOffset, len(list))
+	for i, item := range list {
+		offset, ok := parseStripOffset(item)
+		if !ok {
+			raw, _ := json.Marshal(item)
+			return nil, &StripOffsetError{Index: i, Value: string(raw)}
+		}
+		offsets[i] = offset
+	}
+	return offsets, nil
+}
+
+// 从外层对象中取出偏移列表
+func stripList(object map[string]any) any {
+	lower := make(map[string]any, len(object))
+	for key, value := range object {
+		lower[strings.ToLower(key)] = value
+	}
+	for _, key := range stripListKeys {
+		if value, ok := lower[key].([]any); ok {
+			return value
+		}
+	}
+
+	keys := make([]string, 0, len(object))
+	for key, value := range object {
+		if _, ok := value.([]any); ok {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) != 1 {
+		return nil
+	}
+	sort.Strings(keys)
+	return object[keys[0]]
+}
+
+// 解析单个偏移
+func parseStripOffset(item any) (StripOffset, bool) {
+	switch v := item.(type) {
+	case json.Number, string:
+		// 字符串可能同时包含水平与垂直偏移
+		if s, ok := v.(string); ok && len(strings.Fields(strings.ReplaceAll(s, ",", " "))) > 1 {
+			return parseCSSPosition(s)
+		}
+		x, ok := cssLength(v)
+		return StripOffset{X: x}, ok
+	case []any:
+		if len(v) == 0 || len(v) > 2 {
+			return StripOffset{}, false
+		}
+		x, ok := cssLength(v[0])
+		if !ok {
+			return StripOffset{}, false
+		}
+		y := 0
+		if len(v) == 2 {
+			if y, ok = cssLength(v[1]); !ok {
+				return StripOffset{}, false
+			}
+		}
+		return StripOffset{X: x, Y: y}, true
+	case map[string]any:
+		var xValue, yValue any
+		for key, value := range v {
+			switch strings.ToLower(key) {
+			case "x", "left":
+				xValue = value
+			case "y", "top":
+				yValue = value
+			case "position", "backgroundposition", "background-position", "background_position":
+				if s, ok := value.(string); ok {
+					return parseCSSPosition(s)
+				}
+			}
+		}
+		if xValue == nil {
+			return StripOffset{}, false
+		}
+		x, ok := cssLength(xValue)
+		if !ok {
+			return StripOffset{}, false
+		}
+		y := 0
+		if yValue != nil {
+			if y, ok = cssLength(yValue); !ok {
+				return StripOffset{}, false
+			}
+		}
+		return StripOffset{X: x, Y: y}, true
+	}
+	return StripOffset{}, false
+}
+
+// 解析"-157px -58px"形式的background-position，可带"background-position:"前缀
+func parseCSSPosition(s string) (StripOffset, bool) {
+	s = strings.TrimSpace(s)
+	if i := strings.Index(s, ":"); i >= 0 {
+		s = s[i+1:]
+	}
+	s = strings.TrimSuffix(strings.TrimSpace(s), ";")
+	fields := strings.Fields(strings.ReplaceAll(s, ",", " "))
+	if len(fields) == 0 || len(fields) > 2 {
+		return StripOffset{}, false
+	}
+
+	x, ok := cssLength(fields[0])
+	if !ok {
+		return StripOffset{}, false
+	}
+	y := 0
+	if len(fields) == 2 {
+		if y, ok = cssLength(fields[1]); !ok {
+			return StripOffset{}, false
+		}
+	}
+	return StripOffset{X: x, Y: y}, true
+}
+
+// 解析数值或"-157px"形式的长度，四舍五入到整数像素
+func cssLength(value any) (int, bool) {
+	var f float64
+	switch v := value.(type) {
+	case json.Number:
+		var err error
+		if f, err = v.Float64(); err != nil {
+			return 0, false
+		}
+	case string:
+		var err error
+		if f, err = strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(v), "px"), 64); err != nil {
+			return 0, false
+		}
+	default:
+		return 0, false
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, false
+	}
+	return int(math.Round(f)), true
+}
+
+// RestoreStrips 按CSS偏移还原被打乱的背景：第i个分块取自拼图中(-X, -Y)处、大小为Width×Height的区域，
+// 按行优先顺序放到还原图的第i个位置；分块宽高无效时返回InvalidLayoutError，超出拼图范围时返回StripOffsetError
+func RestoreStrips(sprite image.Image, offsets []StripOffset, layout StripLayout) (*image.RGBA, error) {
+	bounds := sprite.Bounds()
+	if bounds.Empty() {
+		return nil, ErrEmptyImage
+	}
+	if len(offsets) == 0 {
+		return nil, &StripOffsetError{Index: -1, Value: "[]"}
+	}
+
+	columns := layout.Columns
+	if columns <= 0 || columns > len(offsets) {
+		columns = len(offsets)
+	}
+	rows := (len(offsets) + columns - 1) / columns
+	width, height := layout.Width, layout.Height
+	if height <= 0 {
+		height = bounds.Dy() / rows
+	}
+	if width <= 0 {
+		return nil, &InvalidLayoutError{Field: "Width", Value: width}
+	}
+	if height <= 0 {
+		return nil, &InvalidLayoutError{Field: "Height", Value: height}
+	}
+
+	restored := image.NewRGBA(image.Rect(0, 0, width*columns, height*rows))
+	for i, offset := range offsets {
+		src := image.Rect(-offset.X, -offset.Y, -offset.X+width, -offset.Y+height).Add(bounds.Min)
+		if !src.In(bounds) {
+			return nil, &StripOffsetError{Index: i, Value: strconv.Itoa(offset.X) + "px " + strconv.Itoa(offset.Y) + "px"}
+		}
+		dst := image.Rect(0, 0, width, height).Add(image.Pt(i%columns*width, i/columns*height))
+		draw.Draw(restored, dst, sprite, src.Min, draw.Src)
+	}
+	return restored, nil
+}
+
+// RestoreStripsWithByte 解码拼图与JSON偏移列表并还原背景，返回PNG编码的图像，
+// 可直接作为背景传给SlideMatchWithByte或SlideComparison
+func RestoreStripsWithByte(spriteData, offsetsJSON []byte, layout StripLayout) ([]byte, error) {
+	sprite, _, err := image.Decode(bytes.NewReader(spriteData))
+	if err != nil {
+		return nil, &DecodeError{Image: RoleBackground, Err: err}
+	}
+	offsets, err := ParseStripOffsets(offsetsJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	restored, err := RestoreStrips(sprite, offsets, layout)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, restored); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
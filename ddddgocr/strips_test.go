@@ -0,0 +1,139 @@
+package ddddgocr
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"reflect"
+	"testing"
+)
+
+func TestParseStripOffsets(t *testing.T) {
+	want := []StripOffset{{X: -157, Y: -58}, {X: -12, Y: 0}}
+	tests := []struct {
+		name string
+		json string
+		want []StripOffset
+	}{
+		{"objects x/y", `[{"x":-157,"y":-58},{"x":-12,"y":0}]`, want},
+		{"objects left/top", `[{"left":-157,"top":-58},{"LEFT":-12}]`, want},
+		{"objects px strings", `[{"x":"-157px","y":"-58px"},{"x":"-12px","y":"0"}]`, want},
+		{"objects position", `[{"position":"-157px -58px"},{"backgroundPosition":"-12px 0px"}]`, want},
+		{"objects rounded", `[{"x":-156.6,"y":-58.2},{"x":-12.4,"y":0}]`, want},
+		{"pairs", `[[-157,-58],[-12,0]]`, want},
+		{"pairs px strings", `[["-157px","-58px"],["-12px"]]`, want},
+		{"css strings", `["-157px -58px","-12px 0px"]`, want},
+		{"css strings with property", `["background-position: -157px -58px;","background-position:-12px 0"]`, want},
+		{"css strings with comma", `["-157px, -58px","-12px,0"]`, want},
+		{"numbers", `[-157,-12]`, []StripOffset{{X: -157}, {X: -12}}},
+		{"number strings", `["-157px","-12"]`, []StripOffset{{X: -157}, {X: -12}}},
+		{"wrapper offsets", `{"offsets":[[-157,-58],[-12,0]]}`, want},
+		{"wrapper positions", `{"Positions":["-157px -58px","-12px 0px"],"width":10}`, want},
+		{"wrapper data", `{"data":[{"x":-157,"y":-58},{"x":-12,"y":0}]}`, want},
+		{"wrapper single array field", `{"code":0,"strips":[[-157,-58],[-12,0]]}`, want},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseStripOffsets([]byte(tt.json))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseStripOffsetsInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		json  string
+		index int
+	}{
+		{"not json", `[-157,`, -1},
+		{"empty list", `[]`, -1},
+		{"scalar", `42`, -1},
+		{"ambiguous wrapper", `{"a":[1],"b":[2]}`, -1},
+		{"bad item", `[[-157,-58],{"y":3}]`, 1},
+		{"bad length", `["-157px -58px 0px"]`, 0},
+		{"long pair", `[[1,2,3]]`, 0},
+		{"bad unit", `["-157em"]`, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseStripOffsets([]byte(tt.json))
+			var offsetErr *StripOffsetError
+			if !errors.As(err, &offsetErr) || !errors.Is(err, ErrStripOffset) {
+				t.Fatalf("err = %v, want StripOffsetError", err)
+			}
+			if offsetErr.Index != tt.index {
+				t.Errorf("Index = %d, want %d", offsetErr.Index, tt.index)
+			}
+		})
+	}
+}
+
+func TestRestoreStrips(t *testing.T) {
+	// 拼图为两行，每个分块以不同灰度填充，分块i位于拼图中的(10*(i%3), 8*(i/3))
+	sprite := image.NewGray(image.Rect(0, 0, 30, 16))
+	for i := range 6 {
+		for y := range 8 {
+			for x := range 10 {
+				sprite.SetGray(10*(i%3)+x, 8*(i/3)+y, color.Gray{Y: uint8(40 * i)})
+			}
+		}
+	}
+
+	// 还原后的第k个位置取分块order[k]
+	order := []int{4, 0, 5, 2, 1, 3}
+	offsets := make([]StripOffset, len(order))
+	for k, i := range order {
+		offsets[k] = StripOffset{X: -10 * (i % 3), Y: -8 * (i / 3)}
+	}
+
+	restored, err := RestoreStrips(sprite, offsets, StripLayout{Width: 10, Columns: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Bounds() != image.Rect(0, 0, 30, 16) {
+		t.Fatalf("bounds = %v", restored.Bounds())
+	}
+	for k, i := range order {
+		got := restored.RGBAAt(10*(k%3)+5, 8*(k/3)+4).R
+		if got != uint8(40*i) {
+			t.Errorf("position %d: got strip value %d, want %d", k, got, 40*i)
+		}
+	}
+}
+
+func TestRestoreStripsErrors(t *testing.T) {
+	sprite := image.NewGray(image.Rect(0, 0, 30, 16))
+	tests := []struct {
+		name    string
+		offsets []StripOffset
+		layout  StripLayout
+		target  error
+		field   string
+	}{
+		{"missing width", []StripOffset{{}}, StripLayout{}, ErrInvalidLayout, "Width"},
+		{"negative width", []StripOffset{{}}, StripLayout{Width: -10}, ErrInvalidLayout, "Width"},
+		// 高度由拼图推算，行数多于拼图高度时推算结果为0
+		{"derived height", make([]StripOffset, 20), StripLayout{Width: 10, Columns: 1}, ErrInvalidLayout, "Height"},
+		{"out of range", []StripOffset{{X: -25}}, StripLayout{Width: 10}, ErrStripOffset, ""},
+		{"empty offsets", nil, StripLayout{Width: 10}, ErrStripOffset, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := RestoreStrips(sprite, tt.offsets, tt.layout)
+			if !errors.Is(err, tt.target) {
+				t.Fatalf("err = %v, want %v", err, tt.target)
+			}
+			var layoutErr *InvalidLayoutError
+			if errors.As(err, &layoutErr) && layoutErr.Field != tt.field {
+				t.Errorf("Field = %q, want %q", layoutErr.Field, tt.field)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package ddddgocr
+
+import "math"
+
+// SubPixelMode 相关峰值的亚像素细化方式
+type SubPixelMode int
+
+const (
+	SubPixelNone      SubPixelMode = iota // 不细化，SubX、SubY与X1、Y1相同
+	SubPixelQuadratic                     // 3x3邻域二次曲面拟合
+	SubPixelGaussian                      // 横纵方向分别做高斯（对数抛物线）拟合
+)
+
+// RefinePeak 由整数峰值(x, y)及其3x3邻域拟合亚像素峰值位置，at(x, y)为相关图中的得分，
+// width、height为相关图尺寸，越界的邻域取对侧的值，该方向不做偏移
+func RefinePeak(at func(x, y int) float64, x, y, width, height int, mode SubPixelMode) (float64, float64) {
+	if mode == SubPixelNone {
+		return float64(x), float64(y)
+	}
+
+	// n[j+1][i+1]为偏移(i, j)处的得分
+	var n [3][3]float64
+	for j := -1; j <= 1; j++ {
+		for i := -1; i <= 1; i++ {
+			nx, ny := x+i, y+j
+			if nx < 0 || nx >= width {
+				nx = x - i
+			}
+			if ny < 0 || ny >= height {
+				ny = y - j
+			}
+			if nx < 0 || nx >= width {
+				nx = x
+			}
+			if ny < 0 || ny >= height {
+				ny = y
+			}
+			n[j+1][i+1] = at(nx, ny)
+		}
+	}
+
+	var dx, dy float64
+	switch mode {
+	case SubPixelGaussian:
+		dx = gaussianOffset(n[1][0], n[1][1], n[1][2])
+		dy = gaussianOffset(n[0][1], n[1][1], n[2][1])
+	default:
+		dx, dy = quadraticOffset(n)
+	}
+
+	return float64(x) + dx, float64(y) + dy
+}
+
+// 以最小二乘拟合f = a + b·x + c·y + d·x² + e·y² + g·xy，取其极大值点；
+// 3x3网格上各基函数正交，系数可直接由加权和求得
+func quadraticOffset(n [3][3]float64) (float64, float64) {
+	var sx, sy, sxx, syy, sxy float64
+	for j := -1; j <= 1; j++ {
+		for i := -1; i <= 1; i++ {
+			v := n[j+1][i+1]
+			fi, fj := float64(i), float64(j)
+			sx += fi * v
+			sy += fj * v
+			sxx += (fi*fi - 2.0/3) * v
+			syy += (fj*fj - 2.0/3) * v
+			sxy += fi * fj * v
+		}
+	}
+	b, c := sx/6, sy/6
+	d, e := sxx/2, syy/2
+	g := sxy / 4
+
+	// 海森矩阵负定时才是极大值，否则退化为横纵方向分别做抛物线拟合
+	det := 4*d*e - g*g
+	if d >= 0 || e >= 0 || det <= 0 {
+		return parabolaOffset(n[1][0], n[1][1], n[1][2]), parabolaOffset(n[0][1], n[1][1], n[2][1])
+	}
+
+	dx := (-2*e*b + g*c) / det
+	dy := (-2*d*c + g*b) / det
+	return clampOffset(dx), clampOffset(dy)
+}
+
+// 过三点的抛物线顶点相对中点的偏移
+func parabolaOffset(left, center, right float64) float64 {
+	denominator := left - 2*center + right
+	if denominator >= 0 {
+		return 0
+	}
+	return clampOffset((left - right) / (2 * denominator))
+}
+
+// 对数域的抛物线拟合，相当于拟合高斯峰；得分非正时退化为抛物线拟合
+func gaussianOffset(left, center, right float64) float64 {
+	if left <= 0 || center <= 0 || right <= 0 {
+		return parabolaOffset(left, center, right)
+	}
+	return parabolaOffset(math.Log(left), math.Log(center), math.Log(right))
+}
+
+// 峰值为邻域内的最大值，偏移不会超过半个像素
+func clampOffset(offset float64) float64 {
+	return math.Max(-0.5, math.Min(0.5, offset))
+}
@@ -0,0 +1,257 @@
+package ddddgocr
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"math"
+	"time"
+)
+
+// 穷举全部排列的最大拼块数，超过时改为逐次交换的局部搜索
+const tileExhaustiveLimit = 9
+
+// TileResult 拼图交换验证码的求解结果，位置均为行优先序号
+type TileResult struct {
+	Rows, Columns int
+	Swap          [2]int        // 交换后代价最低的两个位置
+	Permutation   []int         // 还原后第i个位置应放置当前第Permutation[i]个位置的拼块
+	Cost          float64       // 最佳排列中接缝两侧梯度不连续度的平均值（灰度级），越小越连续
+	SwapCost      float64       // 最佳交换后的平均不连续度
+	Margin        float64       // 次佳交换与最佳交换的平均不连续度之差
+	Engine        string        // 执行匹配的引擎
+	Elapsed       time.Duration // 匹配耗时
+}
+
+// TileSwap 拼图交换验证码求解，image为被打乱的完整图片，网格大小由TileRows、TileColumns指定
+func TileSwap(imageData []byte, opts ...Option) (*TileResult, error) {
+	return TileSwapContext(context.Background(), imageData, opts...)
+}
+
+// TileSwapContext 可取消的拼图交换验证码求解
+func TileSwapContext(ctx context.Context, imageData []byte, opts ...Option) (*TileResult, error) {
+	start := time.Now()
+
+	// 解码图像
+	img, _, err := image.Decode(bytes.NewReader(imageData))
+	if err != nil {
+		return nil, &DecodeError{Image: RoleBackground, Err: err}
+	}
+
+	return tileSwap(ctx, start, img, NewOptions(opts...))
+}
+
+// TileSwapImage 对已解码图像进行拼图交换验证码求解
+func TileSwapImage(ctx context.Context, img image.Image, opts ...Option) (*TileResult, error) {
+	return tileSwap(ctx, time.Now(), originImage(img), NewOptions(opts...))
+}
+
+// 拼图交换求解流程，start为计时起点：
+// 先求任意两块左右相邻、上下相邻时接缝的不连续度，再搜索使全部接缝不连续度之和最小的排列，
+// 同时单独比较所有单次交换，给出最佳交换与次佳交换的差距
+func tileSwap(ctx context.Context, start time.Time, img image.Image, o *Options) (*TileResult, error) {
+	rows, columns := o.TileRows, o.TileColumns
+	bounds := img.Bounds()
+	width, height := bounds.Dx()/max(columns, 1), bounds.Dy()/max(rows, 1)
+	if rows < 1 || columns < 1 || rows*columns < 2 || width < 2 || height < 2 {
+		return nil, &SizeMismatchError{Target: image.Pt(2*max(columns, 1), 2*max(rows, 1)), Background: bounds.Size()}
+	}
+	n := rows * columns
+
+	// 各拼块的RGB值，tiles[t][c][y*width+x]
+	rgba := toRGBA(img)
+	tiles := make([][3][]float64, n)
+	for t := range n {
+		for c := range 3 {
+			tiles[t][c] = make([]float64, width*height)
+		}
+		x0, y0 := bounds.Min.X+t%columns*width, bounds.Min.Y+t/columns*height
+		for y := range height {
+			for x := range width {
+				p := rgba.RGBAAt(x0+x, y0+y)
+				tiles[t][0][y*width+x] = float64(p.R)
+				tiles[t][1][y*width+x] = float64(p.G)
+				tiles[t][2][y*width+x] = float64(p.B)
+			}
+		}
+	}
+
+	// right[a][b]为b紧贴在a右侧时的不连续度，below[a][b]为b紧贴在a下方时的不连续度
+	right := make([][]float64, n)
+	below := make([][]float64, n)
+	err := parallelRows(ctx, 0, n, o.workerCount(), func(a int) {
+		right[a] = make([]float64, n)
+		below[a] = make([]float64, n)
+		for b := range n {
+			if a == b {
+				continue
+			}
+			right[a][b] = seamCost(tiles[a], tiles[b], height, func(i, k int) int { return i*width + width - 1 - k }, func(i, k int) int { return i*width + k })
+			below[a][b] = seamCost(tiles[a], tiles[b], width, func(i, k int) int { return (height-1-k)*width + i }, func(i, k int) int { return k*width + i })
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 排列的接缝总不连续度，seams为接缝数
+	seams := float64(rows*(columns-1) + columns*(rows-1))
+	cost := func(perm []int) float64 {
+		var sum float64
+		for p, t := range perm {
+			if p%columns > 0 {
+				sum += right[perm[p-1]][t]
+			}
+			if p >= columns {
+				sum += below[perm[p-columns]][t]
+			}
+		}
+		return sum
+	}
+
+	// 所有单次交换
+	identity := make([]int, n)
+	for i := range identity {
+		identity[i] = i
+	}
+	swap := [2]int{0, 1}
+	swapCost, secondCost := math.Inf(1), math.Inf(1)
+	perm := make([]int, n)
+	for i := range n {
+		for j := i + 1; j < n; j++ {
+			copy(perm, identity)
+			perm[i], perm[j] = perm[j], perm[i]
+			c := cost(perm)
+			if c < swapCost {
+				secondCost = swapCost
+				swapCost = c
+				swap = [2]int{i, j}
+			} else if c < secondCost {
+				secondCost = c
+			}
+		}
+	}
+
+	// 最佳排列
+	var best []int
+	var bestCost float64
+	if n <= tileExhaustiveLimit {
+		best, bestCost, err = searchTiles(ctx, n, columns, right, below)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		best, bestCost = improveTiles(n, swap, identity, cost)
+	}
+
+	result := &TileResult{
+		Rows:        rows,
+		Columns:     columns,
+		Swap:        swap,
+		Permutation: best,
+		Cost:        bestCost / seams,
+		SwapCost:    swapCost / seams,
+		Margin:      (secondCost - swapCost) / seams,
+		Engine:      EngineName,
+		Elapsed:     time.Since(start),
+	}
+	if math.IsInf(secondCost, 1) {
+		result.Margin = 0
+	}
+	return result, nil
+}
+
+// 接缝两侧的梯度不连续度：跨越接缝的差值与两侧内部梯度平均值之差的绝对值，对length个位置与三个通道取平均；
+// first(i, k)、second(i, k)为接缝第i个位置上距接缝第k个像素在两侧拼块中的下标
+func seamCost(a, b [3][]float64, length int, first, second func(i, k int) int) float64 {
+	var sum float64
+	for i := range length {
+		for c := range 3 {
+			a0, a1 := a[c][first(i, 0)], a[c][first(i, 1)]
+			b0, b1 := b[c][second(i, 0)], b[c][second(i, 1)]
+			sum += math.Abs((b0 - a0) - ((a0-a1)+(b1-b0))/2)
+		}
+	}
+	return sum / float64(3*length)
+}
+
+// 行优先逐个位置放置拼块的分支限界搜索，部分排列的代价已不低于当前最佳时剪枝
+func searchTiles(ctx context.Context, n, columns int, right, below [][]float64) ([]int, float64, error) {
+	best := make([]int, n)
+	bestCost := math.Inf(1)
+	perm := make([]int, n)
+	used := make([]bool, n)
+	nodes := 0
+
+	var place func(p int, sum float64) error
+	place = func(p int, sum float64) error {
+		if nodes++; nodes%4096 == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		if p == n {
+			bestCost = sum
+			copy(best, perm)
+			return nil
+		}
+		for t := range n {
+			if used[t] {
+				continue
+			}
+			next := sum
+			if p%columns > 0 {
+				next += right[perm[p-1]][t]
+			}
+			if p >= columns {
+				next += below[perm[p-columns]][t]
+			}
+			if next >= bestCost {
+				continue
+			}
+			perm[p] = t
+			used[t] = true
+			if err := place(p+1, next); err != nil {
+				return err
+			}
+			used[t] = false
+		}
+		return nil
+	}
+
+	if err := place(0, 0); err != nil {
+		return nil, 0, err
+	}
+	return best, bestCost, nil
+}
+
+// 从最佳单次交换出发反复执行使代价下降最多的交换，直到无法下降
+func improveTiles(n int, swap [2]int, identity []int, cost func([]int) float64) ([]int, float64) {
+	best := make([]int, n)
+	copy(best, identity)
+	best[swap[0]], best[swap[1]] = best[swap[1]], best[swap[0]]
+	bestCost := cost(best)
+
+	for {
+		improved := false
+		candidate := make([]int, n)
+		var move [2]int
+		moveCost := bestCost
+		for i := range n {
+			for j := i + 1; j < n; j++ {
+				copy(candidate, best)
+				candidate[i], candidate[j] = candidate[j], candidate[i]
+				if c := cost(candidate); c < moveCost {
+					moveCost = c
+					move = [2]int{i, j}
+					improved = true
+				}
+			}
+		}
+		if !improved {
+			return best, bestCost
+		}
+		best[move[0]], best[move[1]] = best[move[1]], best[move[0]]
+		bestCost = moveCost
+	}
+}
@@ -0,0 +1,90 @@
+package ddddgocr
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// 平滑的测试图像，三个通道取不同方向的正弦，任意两块错位时接缝都不连续
+func smoothImage(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			fx, fy := float64(x), float64(y)
+			img.SetRGBA(x, y, color.RGBA{
+				R: uint8(128 + 100*math.Sin(fx/17+fy/29)),
+				G: uint8(128 + 100*math.Cos(fy/13-fx/41)),
+				B: uint8(128 + 100*math.Sin((fx+2*fy)/23)),
+				A: 255,
+			})
+		}
+	}
+	return img
+}
+
+// 按perm打乱拼块：打乱后第p个位置放置原图第perm[p]个拼块
+func shuffleTiles(img *image.RGBA, rows, columns int, perm []int) *image.RGBA {
+	width, height := img.Bounds().Dx()/columns, img.Bounds().Dy()/rows
+	shuffled := image.NewRGBA(img.Bounds())
+	for p, t := range perm {
+		dst := image.Rect(0, 0, width, height).Add(image.Pt(p%columns*width, p/columns*height))
+		draw.Draw(shuffled, dst, img, image.Pt(t%columns*width, t/columns*height), draw.Src)
+	}
+	return shuffled
+}
+
+func TestTileSwapImage(t *testing.T) {
+	tests := []struct {
+		name          string
+		rows, columns int
+		shuffle       []int
+		swap          [2]int
+	}{
+		// 不超过tileExhaustiveLimit时穷举全部排列
+		{"exhaustive swap", 3, 3, []int{0, 1, 2, 3, 7, 5, 6, 4, 8}, [2]int{4, 7}},
+		{"exhaustive shuffle", 3, 3, []int{8, 3, 5, 0, 7, 1, 6, 2, 4}, [2]int{}},
+		{"exhaustive 2x2", 2, 2, []int{3, 2, 1, 0}, [2]int{}},
+		// 超过时从最佳交换出发逐次交换
+		{"greedy swap", 4, 4, []int{0, 1, 2, 3, 4, 5, 13, 7, 8, 9, 10, 11, 12, 6, 14, 15}, [2]int{6, 13}},
+		{"greedy two swaps", 4, 4, []int{0, 9, 2, 3, 4, 5, 6, 7, 8, 1, 10, 15, 12, 13, 14, 11}, [2]int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shuffled := shuffleTiles(smoothImage(40*tt.columns, 30*tt.rows), tt.rows, tt.columns, tt.shuffle)
+			result, err := TileSwapImage(context.Background(), shuffled, WithTileGrid(tt.rows, tt.columns))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// 还原后第i个位置应放置原图第i个拼块，即当前位置p满足shuffle[p] == i
+			want := make([]int, len(tt.shuffle))
+			for p, i := range tt.shuffle {
+				want[i] = p
+			}
+			if !reflect.DeepEqual(result.Permutation, want) {
+				t.Errorf("Permutation = %v, want %v", result.Permutation, want)
+			}
+			if tt.swap != [2]int{} && result.Swap != tt.swap {
+				t.Errorf("Swap = %v, want %v", result.Swap, tt.swap)
+			}
+			if result.Cost > result.SwapCost+1e-9 {
+				t.Errorf("Cost = %v, want at most SwapCost %v", result.Cost, result.SwapCost)
+			}
+		})
+	}
+}
+
+func TestTileSwapInvalidGrid(t *testing.T) {
+	img := smoothImage(20, 20)
+	for _, grid := range [][2]int{{1, 1}, {0, 3}, {3, 11}} {
+		if _, err := TileSwapImage(context.Background(), img, WithTileGrid(grid[0], grid[1])); err == nil {
+			t.Errorf("grid %v: expected error", grid)
+		}
+	}
+}
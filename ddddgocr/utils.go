@@ -1,19 +1,25 @@
 package ddddgocr
 
 import (
+	"bytes"
+	"context"
 	"image"
 	"image/color"
+	"image/draw"
 	"math"
 )
 
-// Canny边缘检测算法
-func cannyEdgeDetection(img *image.Gray, lowThreshold, highThreshold float64) *image.Gray {
+// Canny边缘检测算法，workers为并行协程数
+func cannyEdgeDetection(ctx context.Context, img *image.Gray, lowThreshold, highThreshold float64, workers int) (*image.Gray, error) {
 	bounds := img.Bounds()
 	width := bounds.Dx()
 	height := bounds.Dy()
 
 	// 高斯模糊
-	blurred := gaussianBlur(img)
+	blurred, err := gaussianBlur(ctx, img, workers)
+	if err != nil {
+		return nil, err
+	}
 
 	// 计算梯度
 	gradX := make([][]float64, height)
@@ -29,7 +35,7 @@ func cannyEdgeDetection(img *image.Gray, lowThreshold, highThreshold float64) *i
 	}
 
 	// Sobel算子计算梯度
-	for y := 1; y < height-1; y++ {
+	err = parallelRows(ctx, 1, height-1, workers, func(y int) {
 		for x := 1; x < width-1; x++ {
 			// Sobel X
 			gx := -1*getGrayValue(blurred, x-1, y-1) + 1*getGrayValue(blurred, x+1, y-1) +
@@ -45,22 +51,26 @@ func cannyEdgeDetection(img *image.Gray, lowThreshold, highThreshold float64) *i
 			magnitude[y][x] = math.Sqrt(gx*gx + gy*gy)
 			direction[y][x] = math.Atan2(gy, gx)
 		}
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// 非最大抑制
-	suppressed := nonMaximumSuppression(magnitude, direction, width, height)
+	suppressed, err := nonMaximumSuppression(ctx, magnitude, direction, width, height, workers)
+	if err != nil {
+		return nil, err
+	}
 
 	// 双阈值检测
-	result := doubleThreshold(suppressed, lowThreshold, highThreshold, width, height)
-
-	return result
+	return doubleThreshold(ctx, suppressed, lowThreshold, highThreshold, width, height, workers)
 }
 
 // 双阈值检测
-func doubleThreshold(suppressed [][]float64, lowThreshold, highThreshold float64, width, height int) *image.Gray {
+func doubleThreshold(ctx context.Context, suppressed [][]float64, lowThreshold, highThreshold float64, width, height, workers int) (*image.Gray, error) {
 	result := image.NewGray(image.Rect(0, 0, width, height))
 
-	for y := range height {
+	err := parallelRows(ctx, 0, height, workers, func(y int) {
 		for x := range width {
 			if suppressed[y][x] >= highThreshold {
 				result.Set(x, y, color.Gray{Y: 255})
@@ -70,12 +80,15 @@ func doubleThreshold(suppressed [][]float64, lowThreshold, highThreshold float64
 				result.Set(x, y, color.Gray{Y: 0})
 			}
 		}
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	// 边缘连接（简化版）
+	// 边缘连接（简化版），逐像素原地更新依赖扫描顺序，需顺序执行
 	edgeTracking(result, width, height)
 
-	return result
+	return result, nil
 }
 
 // 边缘跟踪
@@ -108,13 +121,13 @@ func edgeTracking(img *image.Gray, width, height int) {
 }
 
 // 非最大抑制
-func nonMaximumSuppression(magnitude [][]float64, direction [][]float64, width, height int) [][]float64 {
+func nonMaximumSuppression(ctx context.Context, magnitude [][]float64, direction [][]float64, width, height, workers int) ([][]float64, error) {
 	result := make([][]float64, height)
 	for i := range result {
 		result[i] = make([]float64, width)
 	}
 
-	for y := 1; y < height-1; y++ {
+	err := parallelRows(ctx, 1, height-1, workers, func(y int) {
 		for x := 1; x < width-1; x++ {
 			angle := direction[y][x] * 180 / math.Pi
 			if angle < 0 {
@@ -144,9 +157,12 @@ func nonMaximumSuppression(magnitude [][]float64, direction [][]float64, width,
 				result[y][x] = 0
 			}
 		}
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return result
+	return result, nil
 }
 
 // 获取灰度值
@@ -159,7 +175,7 @@ func getGrayValue(img *image.Gray, x, y int) float64 {
 }
 
 // 高斯模糊
-func gaussianBlur(img *image.Gray) *image.Gray {
+func gaussianBlur(ctx context.Context, img *image.Gray, workers int) (*image.Gray, error) {
 	bounds := img.Bounds()
 	width := bounds.Dx()
 	height := bounds.Dy()
@@ -181,7 +197,7 @@ func gaussianBlur(img *image.Gray) *image.Gray {
 
 	result := image.NewGray(bounds)
 
-	for y := 1; y < height-1; y++ {
+	err := parallelRows(ctx, 1, height-1, workers, func(y int) {
 		for x := 1; x < width-1; x++ {
 			var value float64
 			for ky := -1; ky <= 1; ky++ {
@@ -191,40 +207,22 @@ func gaussianBlur(img *image.Gray) *image.Gray {
 			}
 			result.Set(x, y, color.Gray{Y: uint8(math.Max(0, math.Min(255, value)))})
 		}
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return result
+	return result, nil
 }
 
-// 查找极值
-func findExtremes(matrix [][]float64) (maxVal float64, maxX, maxY int, minVal float64, minX, minY int) {
-	if len(matrix) == 0 || len(matrix[0]) == 0 {
-		return
-	}
-
-	maxVal = matrix[0][0]
-	minVal = matrix[0][0]
-	maxX, maxY = 0, 0
-	minX, minY = 0, 0
-
-	for y := range matrix {
-		for x := range matrix[y] {
-			if matrix[y][x] > maxVal {
-				maxVal = matrix[y][x]
-				maxX, maxY = x, y
-			}
-			if matrix[y][x] < minVal {
-				minVal = matrix[y][x]
-				minX, minY = x, y
-			}
-		}
+// 模板匹配 - 标准化交叉相关
+// 窗口和与平方和由积分图求得，每个位置只需计算与模板的互相关项，
+// 背景较大时互相关项改由FFT在频域一次求出；mask不为nil时只统计掩码内的像素
+func matchTemplate(ctx context.Context, background, template *image.Gray, mask *image.Alpha, o *Options) ([][]float64, error) {
+	if mask != nil {
+		return matchTemplateMasked(ctx, background, template, mask, o)
 	}
 
-	return
-}
-
-// 模板匹配 - 标准化交叉相关
-func matchTemplate(background, template *image.Gray) [][]float64 {
 	bgBounds := background.Bounds()
 	tplBounds := template.Bounds()
 
@@ -237,7 +235,7 @@ func matchTemplate(background, template *image.Gray) [][]float64 {
 	resultHeight := bgHeight - tplHeight + 1
 
 	if resultWidth <= 0 || resultHeight <= 0 {
-		return nil
+		return nil, nil
 	}
 
 	result := make([][]float64, resultHeight)
@@ -265,45 +263,72 @@ func matchTemplate(background, template *image.Gray) [][]float64 {
 	}
 	templateStd := math.Sqrt(templateSumSq)
 
-	// 对每个可能的位置进行匹配
-	for y := range resultHeight {
-		for x := range resultWidth {
-			// 计算当前窗口的均值
-			var windowSum float64
-			for wy := range tplHeight {
-				for wx := range tplWidth {
-					windowSum += getGrayValue(background, x+wx, y+wy)
-				}
+	// 频域互相关：使用零均值模板，结果即为Σ(b-μb)(t-μt)
+	var crossTerms [][]float64
+	if o.useFFT(bgWidth) {
+		zeroMean := make([]float64, templatePixels)
+		for y := range tplHeight {
+			for x := range tplWidth {
+				zeroMean[y*tplWidth+x] = getGrayValue(template, x, y) - templateMean
 			}
-			windowMean := windowSum / float64(templatePixels)
+		}
+		var err error
+		crossTerms, err = correlateFFT(ctx, background, zeroMean, tplWidth, tplHeight, resultWidth, resultHeight, o.workerCount())
+		if err != nil {
+			return nil, err
+		}
+	}
 
-			// 计算当前窗口的标准差和相关性
-			var windowSumSq, correlation float64
-			for wy := range tplHeight {
-				for wx := range tplWidth {
-					bgVal := getGrayValue(background, x+wx, y+wy)
-					tplVal := getGrayValue(template, wx, wy)
+	// Σ(b-μb)(t-μt) = Σb·t - μt·Σb，只需记录模板的非零像素（相对背景的偏移）
+	type tplPixel struct {
+		offset int
+		value  float64
+	}
+	nonZero := make([]tplPixel, 0, templatePixels)
+	for y := 0; y < tplHeight && crossTerms == nil; y++ {
+		for x := range tplWidth {
+			if value := getGrayValue(template, x, y); value != 0 {
+				nonZero = append(nonZero, tplPixel{offset: y*background.Stride + x, value: value})
+			}
+		}
+	}
 
-					bgDiff := bgVal - windowMean
-					tplDiff := tplVal - templateMean
+	integral := newIntegralImage(background)
+	n := int64(templatePixels)
 
-					windowSumSq += bgDiff * bgDiff
-					correlation += bgDiff * tplDiff
-				}
+	// 对每个可能的位置进行匹配，各行互不依赖，按行带并行
+	err := parallelRows(ctx, 0, resultHeight, o.workerCount(), func(y int) {
+		for x := range resultWidth {
+			// 窗口的和与平方和，n·Σb² - (Σb)²为整数运算，方差为零时可精确判断
+			windowSum, windowSqSum := integral.window(x, y, tplWidth, tplHeight)
+			varianceNum := n*windowSqSum - windowSum*windowSum
+			if varianceNum <= 0 || templateStd == 0 {
+				result[y][x] = 0
+				continue
 			}
 
-			windowStd := math.Sqrt(windowSumSq)
-
-			// 标准化交叉相关
-			if windowStd > 0 && templateStd > 0 {
-				result[y][x] = correlation / (windowStd * templateStd)
+			var correlation float64
+			if crossTerms != nil {
+				correlation = crossTerms[y][x]
 			} else {
-				result[y][x] = 0
+				base := background.PixOffset(bgBounds.Min.X+x, bgBounds.Min.Y+y)
+				var crossSum float64
+				for _, p := range nonZero {
+					crossSum += float64(background.Pix[base+p.offset]) * p.value
+				}
+				correlation = crossSum - templateMean*float64(windowSum)
 			}
+			windowStd := math.Sqrt(float64(varianceNum) / float64(n))
+
+			// 标准化交叉相关
+			result[y][x] = correlation / (windowStd * templateStd)
 		}
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return result
+	return result, nil
 }
 
 // 将图像转换为RGBA格式
@@ -369,20 +394,23 @@ func cropTransparent(img *image.RGBA) (*image.RGBA, int, int) {
 	return cropped, startY, startX
 }
 
-// 将彩色图像转换为灰度图像
-func toGrayScale(img image.Image) *image.Gray {
+// 将彩色图像转换为灰度图像，workers为并行协程数
+func toGrayScale(ctx context.Context, img image.Image, workers int) (*image.Gray, error) {
 	bounds := img.Bounds()
 	gray := image.NewGray(bounds)
 
-	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+	err := parallelRows(ctx, bounds.Min.Y, bounds.Max.Y, workers, func(y int) {
 		for x := bounds.Min.X; x < bounds.Max.X; x++ {
 			originalColor := img.At(x, y)
 			grayColor := color.GrayModel.Convert(originalColor)
 			gray.Set(x, y, grayColor)
 		}
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return gray
+	return gray, nil
 }
 
 // 将RGBA图像转换为灰度图像
@@ -409,3 +437,43 @@ func absDiff(a, b uint8) uint8 {
 	}
 	return b - a
 }
+
+// 解码目标图像与背景图像
+func decodeImages(targetImageData, backgroundImageData []byte) (image.Image, image.Image, error) {
+	targetImg, _, err := image.Decode(bytes.NewReader(targetImageData))
+	if err != nil {
+		return nil, nil, &DecodeError{Image: RoleTarget, Err: err}
+	}
+
+	backgroundImg, _, err := image.Decode(bytes.NewReader(backgroundImageData))
+	if err != nil {
+		return nil, nil, &DecodeError{Image: RoleBackground, Err: err}
+	}
+
+	return targetImg, backgroundImg, nil
+}
+
+// 将图像平移到原点，匹配流程均假设图像从(0, 0)开始
+func originImage(img image.Image) image.Image {
+	bounds := img.Bounds()
+	if bounds.Min == (image.Point{}) {
+		return img
+	}
+
+	moved := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(moved, moved.Bounds(), img, bounds.Min, draw.Src)
+	return moved
+}
+
+// 复制灰度图中的矩形区域，结果从(0, 0)开始
+func cropGray(img *image.Gray, r image.Rectangle) *image.Gray {
+	if r == img.Bounds() && r.Min == (image.Point{}) {
+		return img
+	}
+
+	cropped := image.NewGray(image.Rect(0, 0, r.Dx(), r.Dy()))
+	for y := range r.Dy() {
+		copy(cropped.Pix[y*cropped.Stride:y*cropped.Stride+r.Dx()], img.Pix[img.PixOffset(r.Min.X, r.Min.Y+y):])
+	}
+	return cropped
+}
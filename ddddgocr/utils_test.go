@@ -0,0 +1,243 @@
+package ddddgocr
+
+import (
+	"context"
+	"errors"
+	"image"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// 直接按定义计算的标准化交叉相关，mask为nil时统计全部像素，方差为零的位置为0
+func naiveNCC(background, template *image.Gray, mask *image.Alpha) [][]float64 {
+	tplWidth, tplHeight := template.Bounds().Dx(), template.Bounds().Dy()
+	resultWidth := background.Bounds().Dx() - tplWidth + 1
+	resultHeight := background.Bounds().Dy() - tplHeight + 1
+	in := func(i, j int) bool {
+		return mask == nil || mask.AlphaAt(i, j).A != 0
+	}
+
+	result := make([][]float64, resultHeight)
+	for y := range resultHeight {
+		result[y] = make([]float64, resultWidth)
+		for x := range resultWidth {
+			var n, bSum, tSum float64
+			for j := range tplHeight {
+				for i := range tplWidth {
+					if in(i, j) {
+						n++
+						bSum += float64(background.GrayAt(x+i, y+j).Y)
+						tSum += float64(template.GrayAt(i, j).Y)
+					}
+				}
+			}
+			if n == 0 {
+				continue
+			}
+			bMean, tMean := bSum/n, tSum/n
+
+			var cross, bVar, tVar float64
+			for j := range tplHeight {
+				for i := range tplWidth {
+					if in(i, j) {
+						b := float64(background.GrayAt(x+i, y+j).Y) - bMean
+						t := float64(template.GrayAt(i, j).Y) - tMean
+						cross += b * t
+						bVar += b * b
+						tVar += t * t
+					}
+				}
+			}
+			if bVar < 1e-9 || tVar < 1e-9 {
+				continue
+			}
+			result[y][x] = cross / math.Sqrt(bVar*tVar)
+		}
+	}
+	return result
+}
+
+// 模板匹配测试用例：随机背景中含一块常数区域，用于检查方差为零的位置
+type matchCase struct {
+	name                                   string
+	bgWidth, bgHeight, tplWidth, tplHeight int
+	sparse                                 bool // 模板大部分为0，类似边缘图
+}
+
+var matchCases = []matchCase{
+	{"small", 23, 17, 5, 4, false},
+	{"odd", 41, 29, 11, 9, false},
+	{"edges", 57, 31, 13, 12, true},
+	{"full", 12, 10, 12, 10, false},
+	{"single", 9, 7, 1, 1, false},
+}
+
+func (c matchCase) images(seed int64) (*image.Gray, *image.Gray) {
+	rng := rand.New(rand.NewSource(seed))
+	background := randomGray(rng, c.bgWidth, c.bgHeight)
+	for y := range min(c.tplHeight+2, c.bgHeight) {
+		for x := range min(c.tplWidth+2, c.bgWidth/2) {
+			background.Pix[y*background.Stride+x] = 77
+		}
+	}
+	template := randomGray(rng, c.tplWidth, c.tplHeight)
+	if c.sparse {
+		for i := range template.Pix {
+			if rng.Intn(5) > 0 {
+				template.Pix[i] = 0
+			} else {
+				template.Pix[i] = 255
+			}
+		}
+	}
+	return background, template
+}
+
+func compareMaps(t *testing.T, name string, got, want [][]float64, tolerance float64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s: %d rows, want %d", name, len(got), len(want))
+	}
+	for y := range want {
+		if len(got[y]) != len(want[y]) {
+			t.Fatalf("%s: row %d has %d values, want %d", name, y, len(got[y]), len(want[y]))
+		}
+		for x := range want[y] {
+			if math.Abs(got[y][x]-want[y][x]) > tolerance {
+				t.Fatalf("%s: [%d][%d] = %v, want %v", name, y, x, got[y][x], want[y][x])
+			}
+		}
+	}
+}
+
+func TestMatchTemplate(t *testing.T) {
+	for i, c := range matchCases {
+		background, template := c.images(int64(i))
+		want := naiveNCC(background, template, nil)
+
+		for _, mode := range []struct {
+			name string
+			fft  FFTMode
+		}{
+			{"integral", FFTNever},
+			{"fft", FFTAlways},
+		} {
+			got, err := matchTemplate(context.Background(), background, template, nil, NewOptions(WithFFT(mode.fft, 0), WithWorkers(1)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			compareMaps(t, c.name+"/"+mode.name, got, want, 1e-6)
+		}
+	}
+}
+
+func TestMatchTemplateMasked(t *testing.T) {
+	for i, c := range matchCases {
+		background, template := c.images(int64(i))
+
+		// 圆形掩码，角落透明
+		rng := rand.New(rand.NewSource(int64(100 + i)))
+		mask := image.NewAlpha(template.Bounds())
+		cx, cy := float64(c.tplWidth)/2, float64(c.tplHeight)/2
+		r := math.Max(cx, cy)
+		for y := range c.tplHeight {
+			for x := range c.tplWidth {
+				dx, dy := float64(x)+0.5-cx, float64(y)+0.5-cy
+				if dx*dx+dy*dy <= r*r || rng.Intn(10) == 0 {
+					mask.Pix[y*mask.Stride+x] = 255
+				}
+			}
+		}
+		want := naiveNCC(background, template, mask)
+
+		for _, mode := range []struct {
+			name string
+			fft  FFTMode
+		}{
+			{"direct", FFTNever},
+			{"fft", FFTAlways},
+		} {
+			got, err := matchTemplate(context.Background(), background, template, mask, NewOptions(WithFFT(mode.fft, 0), WithWorkers(1)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			compareMaps(t, c.name+"/"+mode.name, got, want, 1e-6)
+		}
+	}
+}
+
+// 并行结果与顺序执行逐位一致
+func TestMatchTemplateWorkers(t *testing.T) {
+	for i, c := range matchCases {
+		background, template := c.images(int64(i))
+		mask := image.NewAlpha(template.Bounds())
+		for j := range mask.Pix {
+			if j%3 != 0 {
+				mask.Pix[j] = 255
+			}
+		}
+
+		for _, fft := range []FFTMode{FFTNever, FFTAlways} {
+			for _, m := range []*image.Alpha{nil, mask} {
+				want, err := matchTemplate(context.Background(), background, template, m, NewOptions(WithFFT(fft, 0), WithWorkers(1)))
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, workers := range []int{2, 3, 8} {
+					got, err := matchTemplate(context.Background(), background, template, m, NewOptions(WithFFT(fft, 0), WithWorkers(workers)))
+					if err != nil {
+						t.Fatal(err)
+					}
+					compareMaps(t, c.name, got, want, 0)
+				}
+			}
+		}
+	}
+}
+
+func TestPipelineWorkers(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+	img := image.NewRGBA(image.Rect(0, 0, 67, 45))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(rng.Intn(256))
+	}
+
+	wantGray, err := toGrayScale(context.Background(), img, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantEdges, err := cannyEdgeDetection(context.Background(), wantGray, 50, 150, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, workers := range []int{2, 5, 16} {
+		gray, err := toGrayScale(context.Background(), img, workers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		edges, err := cannyEdgeDetection(context.Background(), gray, 50, 150, workers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(gray.Pix) != string(wantGray.Pix) {
+			t.Fatalf("workers=%d: grayscale differs from sequential result", workers)
+		}
+		if string(edges.Pix) != string(wantEdges.Pix) {
+			t.Fatalf("workers=%d: edges differ from sequential result", workers)
+		}
+	}
+}
+
+// 取消后各步骤返回ctx.Err()
+func TestPipelineCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	img := image.NewGray(image.Rect(0, 0, 20, 20))
+	if _, err := toGrayScale(ctx, img, 2); !errors.Is(err, context.Canceled) {
+		t.Errorf("toGrayScale err = %v, want context.Canceled", err)
+	}
+	if _, err := cannyEdgeDetection(ctx, img, 50, 150, 2); !errors.Is(err, context.Canceled) {
+		t.Errorf("cannyEdgeDetection err = %v, want context.Canceled", err)
+	}
+}
@@ -1,54 +1,117 @@
 package withopencv
 
 import (
-	"errors"
-	"fmt"
+	"context"
 	"image"
 	_ "image/gif"
 	_ "image/jpeg"
 	_ "image/png"
+	"math"
+	"time"
 
 	"github.com/Dainsleif233/ddddGocr/ddddgocr"
 	"gocv.io/x/gocv"
 )
 
+// EngineName OpenCV引擎名称
+const EngineName = "opencv"
+
+// 填充引擎名称与耗时
+func finishResult(result *ddddgocr.SlideResult, start time.Time) *ddddgocr.SlideResult {
+	if result.Scale == 0 {
+		result.Scale = 1
+	}
+	result.Engine = EngineName
+	result.Elapsed = time.Since(start)
+	return result
+}
+
 // SlideMatch 滑块匹配主函数
-func SlideMatch(targetImageData, backgroundImageData []byte) (*ddddgocr.SlideBBox, error) {
+func SlideMatch(targetImageData, backgroundImageData []byte, opts ...ddddgocr.Option) (*ddddgocr.SlideResult, error) {
+	return SlideMatchContext(context.Background(), targetImageData, backgroundImageData, opts...)
+}
+
+// SlideMatchContext 可取消的滑块匹配，OpenCV调用本身无法中断，仅在各步骤之间检查取消
+func SlideMatchContext(ctx context.Context, targetImageData, backgroundImageData []byte, opts ...ddddgocr.Option) (*ddddgocr.SlideResult, error) {
+	start := time.Now()
+
 	// 从字节数据解码为Mat
-	targetMat, err := gocv.IMDecode(targetImageData, gocv.IMReadColor)
+	targetMat, backgroundMat, err := decodeMats(targetImageData, backgroundImageData, true)
 	if err != nil {
-		return nil, fmt.Errorf("解码目标图像失败: %v", err)
+		return nil, err
 	}
 	defer targetMat.Close()
+	defer backgroundMat.Close()
+
+	return slideMatch(ctx, start, targetMat, backgroundMat, ddddgocr.NewOptions(opts...))
+}
+
+// SlideMatchImage 对已解码图像进行滑块匹配
+func SlideMatchImage(ctx context.Context, targetImg, backgroundImg image.Image, opts ...ddddgocr.Option) (*ddddgocr.SlideResult, error) {
+	start := time.Now()
 
-	backgroundMat, err := gocv.IMDecode(backgroundImageData, gocv.IMReadColor)
+	targetMat, backgroundMat, err := imagesToMats(targetImg, backgroundImg, true)
 	if err != nil {
-		return nil, fmt.Errorf("解码背景图像失败: %v", err)
+		return nil, err
 	}
+	defer targetMat.Close()
 	defer backgroundMat.Close()
 
-	// 检查图像尺寸
-	if backgroundMat.Cols() < targetMat.Cols() {
-		return nil, errors.New("背景图片的宽度必须大于等于目标图片的宽度")
-	}
+	return slideMatch(ctx, start, targetMat, backgroundMat, ddddgocr.NewOptions(opts...))
+}
 
-	if backgroundMat.Rows() < targetMat.Rows() {
-		return nil, errors.New("背景图片的高度必须大于等于目标图片的高度")
+// 滑块匹配流程，start为计时起点
+func slideMatch(ctx context.Context, start time.Time, targetMat, backgroundMat gocv.Mat, o *ddddgocr.Options) (*ddddgocr.SlideResult, error) {
+	// 检查图像尺寸
+	if err := ddddgocr.CheckContains(matSize(targetMat), matSize(backgroundMat)); err != nil {
+		return nil, err
 	}
 
 	// 处理透明区域（如果目标图像有透明通道）
-	var processedTarget gocv.Mat
-	var startY int
+	var processedTarget, shapeMask gocv.Mat
+	var startY, startX int
+	var shape *image.Alpha
+	var known *image.Point
 
 	if targetMat.Channels() == 4 {
-		// 有透明通道，需要处理
-		processedTarget, startY = cropTransparentOpenCV(targetMat)
-		defer processedTarget.Close()
+		// 有透明通道，裁剪后透明通道即滑块形状，缩放、旋转与裁剪均以形状为准
+		cropped, sy, sx := cropTransparentOpenCV(targetMat)
+		var err error
+		if shape, err = alphaShape(cropped); err != nil {
+			cropped.Close()
+			return nil, err
+		}
+		// 与纯Go引擎一致，完全不透明或完全透明时视为没有形状
+		opaque := 0
+		for _, a := range shape.Pix {
+			if a != 0 {
+				opaque++
+			}
+		}
+		if opaque == 0 || opaque == len(shape.Pix) {
+			shape = nil
+		}
+		if cropped.Cols() != targetMat.Cols() || cropped.Rows() != targetMat.Rows() {
+			known = &image.Point{X: sx, Y: sy}
+		}
+		processedTarget, shapeMask = splitAlpha(cropped, true)
+		cropped.Close()
+		startY, startX = sy, sx
 	} else {
 		processedTarget = targetMat.Clone()
-		defer processedTarget.Close()
+		shapeMask = gocv.NewMat()
 		startY = 0
 	}
+	defer processedTarget.Close()
+	defer shapeMask.Close()
+	noMask := gocv.NewMat()
+	defer noMask.Close()
+
+	// 带透明通道时按缺口特征排除干扰缺口，需要更多候选；目标图像有透明边距时滑块位置已知
+	k := o.TopK
+	if shape != nil && o.DecoyCandidates > 1 {
+		k = max(k, o.DecoyCandidates)
+	}
 
 	// 转换为灰度图
 	targetGray := gocv.NewMat()
@@ -59,58 +122,170 @@ func SlideMatch(targetImageData, backgroundImageData []byte) (*ddddgocr.SlideBBo
 	defer backgroundGray.Close()
 	gocv.CvtColor(backgroundMat, &backgroundGray, gocv.ColorBGRToGray)
 
-	// Canny边缘检测
-	targetEdges := gocv.NewMat()
-	defer targetEdges.Close()
-	gocv.Canny(targetGray, &targetEdges, 100, 200)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
+	// Canny边缘检测
 	backgroundEdges := gocv.NewMat()
 	defer backgroundEdges.Close()
-	gocv.Canny(backgroundGray, &backgroundEdges, 100, 200)
+	gocv.Canny(backgroundGray, &backgroundEdges, float32(o.Canny.Low), float32(o.Canny.High))
+
+	// 逐个缩放倍数与旋转角度匹配，未设置范围时只匹配原始尺寸与角度
+	result, err := ddddgocr.SearchScales(o, func(scale float64) (*ddddgocr.SlideResult, error) {
+		scaledGray, scaledShape := scalePiece(targetGray, shapeMask, scale)
+		defer scaledGray.Close()
+		defer scaledShape.Close()
+
+		return ddddgocr.SearchAngles(o, func(angle float64) (*ddddgocr.SlideResult, error) {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
+			// 旋转后重新裁剪到形状的不透明区域，结果为旋转后滑块的实际范围
+			pieceGray, pieceShape, offset, err := rotatePiece(scaledGray, scaledShape, angle)
+			if err != nil {
+				pieceGray.Close()
+				pieceShape.Close()
+				return nil, err
+			}
+			defer pieceGray.Close()
+			defer pieceShape.Close()
+			if pieceGray.Cols() > backgroundEdges.Cols() || pieceGray.Rows() > backgroundEdges.Rows() {
+				return nil, nil
+			}
+
+			targetEdges := gocv.NewMat()
+			defer targetEdges.Close()
+			gocv.Canny(pieceGray, &targetEdges, float32(o.Canny.Low), float32(o.Canny.High))
+
+			// 搜索范围，有方向约束时只在滑块所在的行带或列带中匹配
+			piece := image.Pt(int(math.Round(float64(startX)*scale))+offset.X, int(math.Round(float64(startY)*scale))+offset.Y)
+			region := o.SearchRegion(image.Rect(0, 0, backgroundEdges.Cols(), backgroundEdges.Rows()), matSize(targetEdges), &piece)
+			searchEdges := backgroundEdges.Region(region)
+			defer searchEdges.Close()
+
+			// 模板匹配
+			matchResult := gocv.NewMat()
+			defer matchResult.Close()
+			// 各角度使用相同的掩码规则
+			pieceMask := noMask
+			if o.AlphaMask {
+				pieceMask = pieceShape
+			}
+			matchTemplate(searchEdges, targetEdges, pieceMask, &matchResult)
 
-	// 模板匹配
-	matchResult := gocv.NewMat()
-	defer matchResult.Close()
-	gocv.MatchTemplate(backgroundEdges, targetEdges, &matchResult, gocv.TmCcoeffNormed, gocv.NewMat())
+			// 找到最佳匹配位置
+			peaks := findPeaks(matchResult, max(k, 2), targetEdges.Cols(), targetEdges.Rows())
+			refinePeaks(matchResult, peaks, o.SubPixel)
 
-	// 找到最佳匹配位置
-	_, maxVal, _, maxLoc := gocv.MinMaxLoc(matchResult)
+			result := peakResult(peaks, targetEdges.Cols(), targetEdges.Rows(), startY, k, ddddgocr.StrategyEdge)
+			result.Translate(region.Min)
+			return result, nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, ddddgocr.ErrTemplateMatch
+	}
 
-	if maxVal < 0.3 {
-		return nil, errors.New("匹配质量过低")
+	if result.Score < o.MinScore {
+		return nil, &ddddgocr.LowQualityError{Score: result.Score, Threshold: o.MinScore, Strategy: ddddgocr.StrategyEdge}
 	}
 
-	return &ddddgocr.SlideBBox{
-		TargetY: startY,
-		X1:      maxLoc.X,
-		Y1:      maxLoc.Y,
-		X2:      maxLoc.X + targetEdges.Cols(),
-		Y2:      maxLoc.Y + targetEdges.Rows(),
-	}, nil
+	// 综合缺口特征重新排序候选，使用低阈值边缘，淡描边的缺口在标准阈值下轮廓不完整
+	if k > o.TopK {
+		if err := rankGapCandidates(ctx, result, backgroundGray, targetGray, shapeMask, known, o); err != nil {
+			return nil, err
+		}
+	}
+
+	return finishResult(result, start), nil
+}
+
+// 由OpenCV完成低阈值边缘检测与滑块变换后按缺口特征重新排序候选，排序与纯Go引擎共用；
+// 滑块形状按结果的倍数与角度重新变换，与产生候选的裁剪一致
+func rankGapCandidates(ctx context.Context, result *ddddgocr.SlideResult, backgroundGray, targetGray, shapeMask gocv.Mat, known *image.Point, o *ddddgocr.Options) error {
+	scaledGray, scaledShape := scalePiece(targetGray, shapeMask, result.Scale)
+	defer scaledGray.Close()
+	defer scaledShape.Close()
+	pieceGray, pieceShape, offset, err := rotatePiece(scaledGray, scaledShape, result.Angle)
+	if err != nil {
+		pieceGray.Close()
+		pieceShape.Close()
+		return err
+	}
+	defer pieceGray.Close()
+	defer pieceShape.Close()
+	shapeGray, err := toGray(pieceShape)
+	if err != nil {
+		return err
+	}
+	shape := &image.Alpha{Pix: shapeGray.Pix, Stride: shapeGray.Stride, Rect: shapeGray.Rect}
+	var piece *image.Point
+	if known != nil {
+		piece = &image.Point{
+			X: int(math.Round(float64(known.X)*result.Scale)) + offset.X,
+			Y: int(math.Round(float64(known.Y)*result.Scale)) + offset.Y,
+		}
+	}
+
+	edgesMat := gocv.NewMat()
+	defer edgesMat.Close()
+	gocv.Canny(backgroundGray, &edgesMat, float32(o.CannyLow.Low), float32(o.CannyLow.High))
+
+	gray, err := toGray(backgroundGray)
+	if err != nil {
+		return err
+	}
+	edges, err := toGray(edgesMat)
+	if err != nil {
+		return err
+	}
+	return ddddgocr.RankGapCandidates(ctx, result, gray, edges, shape, piece, o)
 }
 
 // SimpleSlideMatch 简单滑块匹配（无透明区域裁剪）
-func SimpleSlideMatch(targetImageData, backgroundImageData []byte) (*ddddgocr.SlideBBox, error) {
+func SimpleSlideMatch(targetImageData, backgroundImageData []byte, opts ...ddddgocr.Option) (*ddddgocr.SlideResult, error) {
+	return SimpleSlideMatchContext(context.Background(), targetImageData, backgroundImageData, opts...)
+}
+
+// SimpleSlideMatchContext 可取消的简单滑块匹配，OpenCV调用本身无法中断，仅在各步骤之间检查取消
+func SimpleSlideMatchContext(ctx context.Context, targetImageData, backgroundImageData []byte, opts ...ddddgocr.Option) (*ddddgocr.SlideResult, error) {
+	start := time.Now()
+
 	// 从字节数据解码为Mat
-	targetMat, err := gocv.IMDecode(targetImageData, gocv.IMReadColor)
+	targetMat, backgroundMat, err := decodeMats(targetImageData, backgroundImageData, false)
 	if err != nil {
-		return nil, fmt.Errorf("解码目标图像失败: %v", err)
+		return nil, err
 	}
 	defer targetMat.Close()
+	defer backgroundMat.Close()
+
+	return simpleSlideMatch(ctx, start, targetMat, backgroundMat, ddddgocr.NewOptions(opts...))
+}
+
+// SimpleSlideMatchImage 对已解码图像进行简单滑块匹配
+func SimpleSlideMatchImage(ctx context.Context, targetImg, backgroundImg image.Image, opts ...ddddgocr.Option) (*ddddgocr.SlideResult, error) {
+	start := time.Now()
 
-	backgroundMat, err := gocv.IMDecode(backgroundImageData, gocv.IMReadColor)
+	targetMat, backgroundMat, err := imagesToMats(targetImg, backgroundImg, false)
 	if err != nil {
-		return nil, fmt.Errorf("解码背景图像失败: %v", err)
+		return nil, err
 	}
+	defer targetMat.Close()
 	defer backgroundMat.Close()
 
-	// 检查图像尺寸
-	if backgroundMat.Cols() < targetMat.Cols() {
-		return nil, errors.New("背景图片的宽度必须大于等于目标图片的宽度")
-	}
+	return simpleSlideMatch(ctx, start, targetMat, backgroundMat, ddddgocr.NewOptions(opts...))
+}
 
-	if backgroundMat.Rows() < targetMat.Rows() {
-		return nil, errors.New("背景图片的高度必须大于等于目标图片的高度")
+// 简单滑块匹配流程，start为计时起点
+func simpleSlideMatch(ctx context.Context, start time.Time, targetMat, backgroundMat gocv.Mat, o *ddddgocr.Options) (*ddddgocr.SlideResult, error) {
+	// 检查图像尺寸
+	if err := ddddgocr.CheckContains(matSize(targetMat), matSize(backgroundMat)); err != nil {
+		return nil, err
 	}
 
 	// 转换为灰度图
@@ -122,72 +297,134 @@ func SimpleSlideMatch(targetImageData, backgroundImageData []byte) (*ddddgocr.Sl
 	defer backgroundGray.Close()
 	gocv.CvtColor(backgroundMat, &backgroundGray, gocv.ColorBGRToGray)
 
-	// Canny边缘检测
-	targetEdges := gocv.NewMat()
-	defer targetEdges.Close()
-	gocv.Canny(targetGray, &targetEdges, 100, 200)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
+	// Canny边缘检测
 	backgroundEdges := gocv.NewMat()
 	defer backgroundEdges.Close()
-	gocv.Canny(backgroundGray, &backgroundEdges, 100, 200)
+	gocv.Canny(backgroundGray, &backgroundEdges, float32(o.Canny.Low), float32(o.Canny.High))
+
+	noMask := gocv.NewMat()
+	defer noMask.Close()
+
+	// 逐个缩放倍数匹配，未设置尺度范围时只匹配原始尺寸
+	result, err := ddddgocr.SearchScales(o, func(scale float64) (*ddddgocr.SlideResult, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		scaledGray, scaledMask := scalePiece(targetGray, noMask, scale)
+		defer scaledGray.Close()
+		defer scaledMask.Close()
+		if scaledGray.Cols() > backgroundEdges.Cols() || scaledGray.Rows() > backgroundEdges.Rows() {
+			return nil, nil
+		}
+
+		targetEdges := gocv.NewMat()
+		defer targetEdges.Close()
+		gocv.Canny(scaledGray, &targetEdges, float32(o.Canny.Low), float32(o.Canny.High))
+
+		// 搜索范围，未裁剪透明区域，滑块自身偏移未知
+		region := o.SearchRegion(image.Rect(0, 0, backgroundEdges.Cols(), backgroundEdges.Rows()), matSize(targetEdges), nil)
+		searchEdges := backgroundEdges.Region(region)
+		defer searchEdges.Close()
+
+		// 模板匹配
+		matchResult := gocv.NewMat()
+		defer matchResult.Close()
+		gocv.MatchTemplate(searchEdges, targetEdges, &matchResult, gocv.TmCcoeffNormed, noMask)
 
-	// 模板匹配
-	matchResult := gocv.NewMat()
-	defer matchResult.Close()
-	gocv.MatchTemplate(backgroundEdges, targetEdges, &matchResult, gocv.TmCcoeffNormed, gocv.NewMat())
+		// 找到最佳匹配位置
+		peaks := findPeaks(matchResult, max(o.TopK, 2), targetEdges.Cols(), targetEdges.Rows())
+		refinePeaks(matchResult, peaks, o.SubPixel)
 
-	// 找到最佳匹配位置
-	_, maxVal, _, maxLoc := gocv.MinMaxLoc(matchResult)
+		result := peakResult(peaks, targetEdges.Cols(), targetEdges.Rows(), 0, o.TopK, ddddgocr.StrategyEdge)
+		result.Translate(region.Min)
+		return result, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, ddddgocr.ErrTemplateMatch
+	}
 
-	if maxVal < 0.3 {
-		return nil, errors.New("匹配质量过低")
+	if result.Score < o.MinScore {
+		return nil, &ddddgocr.LowQualityError{Score: result.Score, Threshold: o.MinScore, Strategy: ddddgocr.StrategyEdge}
 	}
 
-	return &ddddgocr.SlideBBox{
-		TargetY: 0,
-		X1:      maxLoc.X,
-		Y1:      maxLoc.Y,
-		X2:      maxLoc.X + targetEdges.Cols(),
-		Y2:      maxLoc.Y + targetEdges.Rows(),
-	}, nil
+	return finishResult(result, start), nil
 }
 
 // EnhancedSlideMatch 增强版滑块匹配
-func EnhancedSlideMatch(targetImageData, backgroundImageData []byte) (*ddddgocr.SlideBBox, error) {
+func EnhancedSlideMatch(targetImageData, backgroundImageData []byte, opts ...ddddgocr.Option) (*ddddgocr.SlideResult, error) {
+	return EnhancedSlideMatchContext(context.Background(), targetImageData, backgroundImageData, opts...)
+}
+
+// EnhancedSlideMatchContext 可取消的增强版滑块匹配，OpenCV调用本身无法中断，仅在各步骤之间检查取消
+func EnhancedSlideMatchContext(ctx context.Context, targetImageData, backgroundImageData []byte, opts ...ddddgocr.Option) (*ddddgocr.SlideResult, error) {
+	start := time.Now()
+
 	// 从字节数据解码为Mat
-	targetMat, err := gocv.IMDecode(targetImageData, gocv.IMReadColor)
+	targetMat, backgroundMat, err := decodeMats(targetImageData, backgroundImageData, true)
 	if err != nil {
-		return nil, fmt.Errorf("解码目标图像失败: %v", err)
+		return nil, err
 	}
 	defer targetMat.Close()
+	defer backgroundMat.Close()
+
+	return enhancedSlideMatch(ctx, start, targetMat, backgroundMat, ddddgocr.NewOptions(opts...))
+}
 
-	backgroundMat, err := gocv.IMDecode(backgroundImageData, gocv.IMReadColor)
+// EnhancedSlideMatchImage 对已解码图像进行增强版滑块匹配
+func EnhancedSlideMatchImage(ctx context.Context, targetImg, backgroundImg image.Image, opts ...ddddgocr.Option) (*ddddgocr.SlideResult, error) {
+	start := time.Now()
+
+	targetMat, backgroundMat, err := imagesToMats(targetImg, backgroundImg, true)
 	if err != nil {
-		return nil, fmt.Errorf("解码背景图像失败: %v", err)
+		return nil, err
 	}
+	defer targetMat.Close()
 	defer backgroundMat.Close()
 
-	// 检查图像尺寸
-	if backgroundMat.Cols() < targetMat.Cols() {
-		return nil, errors.New("背景图片的宽度必须大于等于目标图片的宽度")
-	}
+	return enhancedSlideMatch(ctx, start, targetMat, backgroundMat, ddddgocr.NewOptions(opts...))
+}
 
-	if backgroundMat.Rows() < targetMat.Rows() {
-		return nil, errors.New("背景图片的高度必须大于等于目标图片的高度")
+// 增强版滑块匹配流程，start为计时起点
+func enhancedSlideMatch(ctx context.Context, start time.Time, targetMat, backgroundMat gocv.Mat, o *ddddgocr.Options) (*ddddgocr.SlideResult, error) {
+	// 检查图像尺寸
+	if err := ddddgocr.CheckContains(matSize(targetMat), matSize(backgroundMat)); err != nil {
+		return nil, err
 	}
 
 	// 处理透明区域（如果有的话）
-	var processedTarget gocv.Mat
+	var processedTarget, mask gocv.Mat
+	var shape *image.Alpha
+	var piece *image.Point
 	var startY int
 
 	if targetMat.Channels() == 4 {
-		processedTarget, startY = cropTransparentOpenCV(targetMat)
-		defer processedTarget.Close()
+		cropped, sy, sx := cropTransparentOpenCV(targetMat)
+		processedTarget, mask = splitAlpha(cropped, o.AlphaMask)
+		var err error
+		shape, err = alphaShape(cropped)
+		cropped.Close()
+		if err != nil {
+			processedTarget.Close()
+			mask.Close()
+			return nil, err
+		}
+		startY = sy
+		piece = &image.Point{X: sx, Y: sy}
 	} else {
 		processedTarget = targetMat.Clone()
-		defer processedTarget.Close()
+		mask = gocv.NewMat()
 		startY = 0
 	}
+	defer processedTarget.Close()
+	defer mask.Close()
 
 	// 转换为灰度图
 	targetGray := gocv.NewMat()
@@ -198,103 +435,152 @@ func EnhancedSlideMatch(targetImageData, backgroundImageData []byte) (*ddddgocr.
 	defer backgroundGray.Close()
 	gocv.CvtColor(backgroundMat, &backgroundGray, gocv.ColorBGRToGray)
 
-	results := make([]*ddddgocr.SlideBBox, 0)
+	// 搜索范围，各策略的模板尺寸相同，共用同一区域
+	region := o.SearchRegion(image.Rect(0, 0, backgroundGray.Cols(), backgroundGray.Rows()), matSize(targetGray), piece)
+	searchGray := backgroundGray.Region(region)
+	defer searchGray.Close()
+
+	results := make([]*ddddgocr.SlideResult, 0)
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
 	// 策略1: 直接灰度模板匹配
 	matchResult1 := gocv.NewMat()
 	defer matchResult1.Close()
-	gocv.MatchTemplate(backgroundGray, targetGray, &matchResult1, gocv.TmCcoeffNormed, gocv.NewMat())
-	_, maxVal1, _, maxLoc1 := gocv.MinMaxLoc(matchResult1)
-
-	if maxVal1 > 0.6 {
-		results = append(results, &ddddgocr.SlideBBox{
-			TargetY: startY,
-			X1:      maxLoc1.X,
-			Y1:      maxLoc1.Y,
-			X2:      maxLoc1.X + targetGray.Cols(),
-			Y2:      maxLoc1.Y + targetGray.Rows(),
-		})
+	matchTemplate(searchGray, targetGray, mask, &matchResult1)
+	peaks1 := findPeaks(matchResult1, max(o.TopK, 2), targetGray.Cols(), targetGray.Rows())
+	refinePeaks(matchResult1, peaks1, o.SubPixel)
+
+	if peaks1[0].score > o.GrayMinScore {
+		results = append(results, peakResult(peaks1, targetGray.Cols(), targetGray.Rows(), startY, o.TopK, ddddgocr.StrategyGray))
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
 	// 策略2: 低阈值边缘检测匹配
 	targetEdges1 := gocv.NewMat()
 	defer targetEdges1.Close()
-	gocv.Canny(targetGray, &targetEdges1, 30, 80)
+	gocv.Canny(targetGray, &targetEdges1, float32(o.CannyLow.Low), float32(o.CannyLow.High))
 
 	backgroundEdges1 := gocv.NewMat()
 	defer backgroundEdges1.Close()
-	gocv.Canny(backgroundGray, &backgroundEdges1, 30, 80)
+	gocv.Canny(backgroundGray, &backgroundEdges1, float32(o.CannyLow.Low), float32(o.CannyLow.High))
+
+	searchEdges1 := backgroundEdges1.Region(region)
+	defer searchEdges1.Close()
 
 	matchResult2 := gocv.NewMat()
 	defer matchResult2.Close()
-	gocv.MatchTemplate(backgroundEdges1, targetEdges1, &matchResult2, gocv.TmCcoeffNormed, gocv.NewMat())
-	_, maxVal2, _, maxLoc2 := gocv.MinMaxLoc(matchResult2)
-
-	if maxVal2 > 0.3 {
-		results = append(results, &ddddgocr.SlideBBox{
-			TargetY: startY,
-			X1:      maxLoc2.X,
-			Y1:      maxLoc2.Y,
-			X2:      maxLoc2.X + targetEdges1.Cols(),
-			Y2:      maxLoc2.Y + targetEdges1.Rows(),
-		})
+	matchTemplate(searchEdges1, targetEdges1, mask, &matchResult2)
+	peaks2 := findPeaks(matchResult2, max(o.TopK, 2), targetEdges1.Cols(), targetEdges1.Rows())
+	refinePeaks(matchResult2, peaks2, o.SubPixel)
+
+	if peaks2[0].score > o.EdgeLowMinScore {
+		results = append(results, peakResult(peaks2, targetEdges1.Cols(), targetEdges1.Rows(), startY, o.TopK, ddddgocr.StrategyEdgeLow))
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
 	// 策略3: 中等阈值边缘检测匹配
 	targetEdges2 := gocv.NewMat()
 	defer targetEdges2.Close()
-	gocv.Canny(targetGray, &targetEdges2, 50, 150)
+	gocv.Canny(targetGray, &targetEdges2, float32(o.CannyMid.Low), float32(o.CannyMid.High))
 
 	backgroundEdges2 := gocv.NewMat()
 	defer backgroundEdges2.Close()
-	gocv.Canny(backgroundGray, &backgroundEdges2, 50, 150)
+	gocv.Canny(backgroundGray, &backgroundEdges2, float32(o.CannyMid.Low), float32(o.CannyMid.High))
+
+	searchEdges2 := backgroundEdges2.Region(region)
+	defer searchEdges2.Close()
 
 	matchResult3 := gocv.NewMat()
 	defer matchResult3.Close()
-	gocv.MatchTemplate(backgroundEdges2, targetEdges2, &matchResult3, gocv.TmCcoeffNormed, gocv.NewMat())
-	_, maxVal3, _, maxLoc3 := gocv.MinMaxLoc(matchResult3)
-
-	if maxVal3 > 0.2 {
-		results = append(results, &ddddgocr.SlideBBox{
-			TargetY: startY,
-			X1:      maxLoc3.X,
-			Y1:      maxLoc3.Y,
-			X2:      maxLoc3.X + targetEdges2.Cols(),
-			Y2:      maxLoc3.Y + targetEdges2.Rows(),
-		})
+	matchTemplate(searchEdges2, targetEdges2, mask, &matchResult3)
+	peaks3 := findPeaks(matchResult3, max(o.TopK, 2), targetEdges2.Cols(), targetEdges2.Rows())
+	refinePeaks(matchResult3, peaks3, o.SubPixel)
+
+	if peaks3[0].score > o.EdgeMidMinScore {
+		results = append(results, peakResult(peaks3, targetEdges2.Cols(), targetEdges2.Rows(), startY, o.TopK, ddddgocr.StrategyEdgeMid))
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
 	// 策略4: 使用SIFT特征匹配（可选）
 	if len(results) == 0 {
-		siftResult := siftFeatureMatch(backgroundGray, targetGray)
+		siftResult := siftFeatureMatch(searchGray, targetGray, mask, startY, o)
 		if siftResult != nil {
 			results = append(results, siftResult)
 		}
 	}
 
+	// 策略5: 轮廓形状匹配（滑块带透明通道时），以灰度、低阈值边缘与滑块轮廓匹配的峰值作为搜索窗口
+	if shape != nil {
+		outline, err := outlineAnchors(searchEdges1, shape, contourAnchors)
+		if err != nil {
+			return nil, err
+		}
+		anchors := append(peakBoxes(peaks1, targetGray.Cols(), targetGray.Rows()), peakBoxes(peaks2, targetEdges1.Cols(), targetEdges1.Rows())...)
+		anchors = append(anchors, outline...)
+		contourResult, err := matchContour(ctx, backgroundEdges1, region, shape, anchors, startY, o)
+		if err != nil {
+			return nil, err
+		}
+		if contourResult != nil && contourResult.Score >= o.ContourMinScore {
+			results = append(results, contourResult)
+		}
+	}
+
+	// 策略6: 差分匹配（寻找缺口），与纯Go引擎共用
+	diffResult, err := slotByDifference(ctx, searchGray, targetGray, o)
+	if err != nil {
+		return nil, err
+	}
+	if diffResult != nil {
+		results = append(results, diffResult)
+	}
+
 	if len(results) == 0 {
-		return nil, errors.New("所有匹配策略都失败了")
+		return nil, ddddgocr.ErrNoMatch
 	}
 
-	// 选择最可信的结果
-	var bestResult *ddddgocr.SlideBBox
+	// 平移回背景坐标
 	for _, result := range results {
-		if result.X1 > 0 {
-			if bestResult == nil || result.X1 < bestResult.X1 {
-				bestResult = result
-			}
-		}
+		result.Translate(region.Min)
 	}
 
-	if bestResult == nil {
-		bestResult = results[0]
-	}
+	// 融合各策略的结果，与纯Go引擎一致
+	bestResult := ddddgocr.FuseResults(results, o)
 
-	return bestResult, nil
+	return finishResult(bestResult, start), nil
 }
 
-// cropTransparentOpenCV 使用OpenCV裁剪透明区域
-func cropTransparentOpenCV(img gocv.Mat) (gocv.Mat, int) {
+// 将灰度Mat转换为图像后进行差分匹配
+func slotByDifference(ctx context.Context, searchGray, targetGray gocv.Mat, o *ddddgocr.Options) (*ddddgocr.SlideResult, error) {
+	// Region得到的子矩阵不连续，复制后再转换
+	searchCopy := searchGray.Clone()
+	defer searchCopy.Close()
+
+	background, err := toGray(searchCopy)
+	if err != nil {
+		return nil, err
+	}
+	target, err := toGray(targetGray)
+	if err != nil {
+		return nil, err
+	}
+	return ddddgocr.FindSlotByDifference(ctx, background, target, o)
+}
+
+// cropTransparentOpenCV 使用OpenCV裁剪透明区域，返回裁剪结果与其起始Y、X
+func cropTransparentOpenCV(img gocv.Mat) (gocv.Mat, int, int) {
 	// 分离通道
 	channels := gocv.Split(img)
 	defer func() {
@@ -305,7 +591,7 @@ func cropTransparentOpenCV(img gocv.Mat) (gocv.Mat, int) {
 
 	if len(channels) < 4 {
 		// 没有透明通道，返回原图
-		return img.Clone(), 0
+		return img.Clone(), 0, 0
 	}
 
 	alphaMat := channels[3] // 透明通道
@@ -317,7 +603,7 @@ func cropTransparentOpenCV(img gocv.Mat) (gocv.Mat, int) {
 
 	if nonZeroPoints.Rows() == 0 {
 		// 没有非透明像素，返回原图
-		return img.Clone(), 0
+		return img.Clone(), 0, 0
 	}
 
 	// 计算边界框
@@ -328,23 +614,26 @@ func cropTransparentOpenCV(img gocv.Mat) (gocv.Mat, int) {
 	// 裁剪图像
 	croppedImg := img.Region(boundingRect)
 
-	return croppedImg, boundingRect.Min.Y
+	return croppedImg, boundingRect.Min.Y, boundingRect.Min.X
 }
 
-// siftFeatureMatch 使用SIFT特征进行匹配
-func siftFeatureMatch(background, target gocv.Mat) *ddddgocr.SlideBBox {
+// siftFeatureMatch 使用SIFT特征进行匹配，最近邻与次近邻做比值检验后以RANSAC估计滑块位置；
+// mask非空时只在滑块的不透明区域检测关键点
+func siftFeatureMatch(background, target, mask gocv.Mat, targetY int, o *ddddgocr.Options) *ddddgocr.SlideResult {
 	// 创建SIFT检测器
 	sift := gocv.NewSIFT()
 	defer sift.Close()
 
 	// 检测关键点和描述符
-	kp1, desc1 := sift.DetectAndCompute(target, gocv.NewMat())
+	kp1, desc1 := sift.DetectAndCompute(target, mask)
 	defer desc1.Close()
 
-	kp2, desc2 := sift.DetectAndCompute(background, gocv.NewMat())
+	noMask := gocv.NewMat()
+	defer noMask.Close()
+	kp2, desc2 := sift.DetectAndCompute(background, noMask)
 	defer desc2.Close()
 
-	if desc1.Rows() == 0 || desc2.Rows() == 0 {
+	if desc1.Rows() == 0 || desc2.Rows() < 2 {
 		return nil
 	}
 
@@ -352,70 +641,60 @@ func siftFeatureMatch(background, target gocv.Mat) *ddddgocr.SlideBBox {
 	matcher := gocv.NewBFMatcher()
 	defer matcher.Close()
 
-	// 进行匹配
-	matches := matcher.Match(desc1, desc2)
-
-	if len(matches) < 4 {
-		return nil
-	}
-
-	// 提取匹配点
-	srcPoints := make([]image.Point, 0)
-	dstPoints := make([]image.Point, 0)
-
-	for _, match := range matches {
-		if match.Distance < 0.7 { // 过滤好的匹配
-			srcPoint := image.Point{X: int(kp1[match.QueryIdx].X), Y: int(kp1[match.QueryIdx].Y)}
-			dstPoint := image.Point{X: int(kp2[match.TrainIdx].X), Y: int(kp2[match.TrainIdx].Y)}
-
-			srcPoints = append(srcPoints, srcPoint)
-			dstPoints = append(dstPoints, dstPoint)
+	// 取最近邻与次近邻，比值检验过滤有歧义的匹配
+	matches := make([]ddddgocr.FeatureMatch, 0)
+	for _, pair := range matcher.KnnMatch(desc1, desc2, 2) {
+		if len(pair) < 2 || pair[0].Distance >= o.FeatureRatio*pair[1].Distance {
+			continue
 		}
+		src := kp1[pair[0].QueryIdx]
+		dst := kp2[pair[0].TrainIdx]
+		matches = append(matches, ddddgocr.FeatureMatch{SrcX: src.X, SrcY: src.Y, DstX: dst.X, DstY: dst.Y})
 	}
 
-	if len(srcPoints) < 4 {
-		return nil
-	}
-
-	// 使用RANSAC找到最佳匹配位置
-	// 这里简化处理，取平均偏移量
-	var sumX, sumY int
-	for i := range srcPoints {
-		sumX += dstPoints[i].X - srcPoints[i].X
-		sumY += dstPoints[i].Y - srcPoints[i].Y
-	}
-
-	avgX := sumX / len(srcPoints)
-	avgY := sumY / len(srcPoints)
-
-	return &ddddgocr.SlideBBox{
-		TargetY: 0,
-		X1:      avgX,
-		Y1:      avgY,
-		X2:      avgX + target.Cols(),
-		Y2:      avgY + target.Rows(),
-	}
+	return ddddgocr.FeatureResult(matches, matSize(target), targetY, o, ddddgocr.StrategySIFT)
 }
 
 // SlideComparison 坑位匹配
 // 通过比较两张相同尺寸的图片，找出差异区域来定位坑位位置
-func SlideComparison(targetImageData, backgroundImageData []byte) (*ddddgocr.SlideBBox, error) {
+func SlideComparison(targetImageData, backgroundImageData []byte, opts ...ddddgocr.Option) (*ddddgocr.SlideResult, error) {
+	return SlideComparisonContext(context.Background(), targetImageData, backgroundImageData, opts...)
+}
+
+// SlideComparisonContext 可取消的坑位匹配，仅在各步骤之间检查取消
+func SlideComparisonContext(ctx context.Context, targetImageData, backgroundImageData []byte, opts ...ddddgocr.Option) (*ddddgocr.SlideResult, error) {
+	start := time.Now()
+
 	// 从字节数据解码为Mat
-	targetMat, err := gocv.IMDecode(targetImageData, gocv.IMReadColor)
+	targetMat, backgroundMat, err := decodeMats(targetImageData, backgroundImageData, false)
 	if err != nil {
-		return nil, fmt.Errorf("解码目标图像失败: %v", err)
+		return nil, err
 	}
 	defer targetMat.Close()
+	defer backgroundMat.Close()
+
+	return slideComparison(ctx, start, targetMat, backgroundMat, ddddgocr.NewOptions(opts...))
+}
+
+// SlideComparisonImage 对已解码图像进行坑位匹配
+func SlideComparisonImage(ctx context.Context, targetImg, backgroundImg image.Image, opts ...ddddgocr.Option) (*ddddgocr.SlideResult, error) {
+	start := time.Now()
 
-	backgroundMat, err := gocv.IMDecode(backgroundImageData, gocv.IMReadColor)
+	targetMat, backgroundMat, err := imagesToMats(targetImg, backgroundImg, false)
 	if err != nil {
-		return nil, fmt.Errorf("解码背景图像失败: %v", err)
+		return nil, err
 	}
+	defer targetMat.Close()
 	defer backgroundMat.Close()
 
+	return slideComparison(ctx, start, targetMat, backgroundMat, ddddgocr.NewOptions(opts...))
+}
+
+// 坑位匹配流程，start为计时起点
+func slideComparison(ctx context.Context, start time.Time, targetMat, backgroundMat gocv.Mat, o *ddddgocr.Options) (*ddddgocr.SlideResult, error) {
 	// 检查图像尺寸是否相等
-	if targetMat.Cols() != backgroundMat.Cols() || targetMat.Rows() != backgroundMat.Rows() {
-		return nil, errors.New("图片尺寸不相等")
+	if err := ddddgocr.CheckEqual(matSize(targetMat), matSize(backgroundMat)); err != nil {
+		return nil, err
 	}
 
 	// 计算差异图像
@@ -432,44 +711,130 @@ func SlideComparison(targetImageData, backgroundImageData []byte) (*ddddgocr.Sli
 	// 二值化处理
 	binaryMat := gocv.NewMat()
 	defer binaryMat.Close()
-	gocv.Threshold(grayMat, &binaryMat, 80, 255, gocv.ThresholdBinary)
+	gocv.Threshold(grayMat, &binaryMat, float32(o.DiffThreshold), 255, gocv.ThresholdBinary)
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// 开运算去除JPEG噪点等零散差异
+	if o.DiffOpenSize > 1 {
+		kernel := gocv.GetStructuringElement(gocv.MorphRect, image.Pt(o.DiffOpenSize, o.DiffOpenSize))
+		defer kernel.Close()
+		gocv.MorphologyEx(binaryMat, &binaryMat, gocv.MorphOpen, kernel)
+	}
+
+	// 差异图中的8连通区域，标签0为背景
+	labels := gocv.NewMat()
+	defer labels.Close()
+	stats := gocv.NewMat()
+	defer stats.Close()
+	centroids := gocv.NewMat()
+	defer centroids.Close()
+	n := gocv.ConnectedComponentsWithStats(binaryMat, &labels, &stats, &centroids)
+
+	components := make([]ddddgocr.DiffComponent, 0, max(n-1, 0))
+	for i := 1; i < n; i++ {
+		left := int(stats.GetIntAt(i, int(gocv.CC_STAT_LEFT)))
+		top := int(stats.GetIntAt(i, int(gocv.CC_STAT_TOP)))
+		width := int(stats.GetIntAt(i, int(gocv.CC_STAT_WIDTH)))
+		height := int(stats.GetIntAt(i, int(gocv.CC_STAT_HEIGHT)))
+		components = append(components, ddddgocr.DiffComponent{
+			Bounds: image.Rect(left, top, left+width, top+height),
+			Area:   int(stats.GetIntAt(i, int(gocv.CC_STAT_AREA))),
+		})
+	}
+
+	result, err := ddddgocr.ComparisonResult(components, o)
+	if err != nil {
+		return nil, err
+	}
+	return finishResult(result, start), nil
+}
 
-	var startX, startY int = 0, 0
+// 相关图中的峰值
+type peak struct {
+	loc        image.Point
+	score      float64
+	subX, subY float64 // 亚像素细化后的位置
+}
 
-	// 按列扫描寻找差异区域
-	width := binaryMat.Cols()
-	height := binaryMat.Rows()
+// findPeaks 非极大值抑制查找前k个峰值，每次取最大值后屏蔽其周围模板尺寸范围
+func findPeaks(matchResult gocv.Mat, k, width, height int) []peak {
+	masked := matchResult.Clone()
+	defer masked.Close()
 
-	for x := 0; x < width; x++ {
-		count := 0
+	peaks := make([]peak, 0, k)
+	for len(peaks) < k {
+		_, maxVal, _, maxLoc := gocv.MinMaxLoc(masked)
+		// 相关系数不小于-1，低于-1说明全部位置均已被屏蔽
+		if maxVal < -1 {
+			break
+		}
+		peaks = append(peaks, peak{loc: maxLoc, score: float64(maxVal), subX: float64(maxLoc.X), subY: float64(maxLoc.Y)})
 
-		for y := 0; y < height; y++ {
-			pixelValue := binaryMat.GetUCharAt(y, x)
+		rect := image.Rect(maxLoc.X-width+1, maxLoc.Y-height+1, maxLoc.X+width, maxLoc.Y+height).
+			Intersect(image.Rect(0, 0, masked.Cols(), masked.Rows()))
+		region := masked.Region(rect)
+		region.SetTo(gocv.NewScalar(-2, 0, 0, 0))
+		region.Close()
+	}
+	return peaks
+}
 
-			// 如果像素不是黑色（即存在差异）
-			if pixelValue != 0 {
-				count++
-			}
+// refinePeaks 在相关图上对各峰值做亚像素细化
+func refinePeaks(matchResult gocv.Mat, peaks []peak, mode ddddgocr.SubPixelMode) {
+	if mode == ddddgocr.SubPixelNone {
+		return
+	}
 
-			// 如果连续发现5个差异像素且还未设置startY
-			if count >= 5 && startY == 0 {
-				if y >= 5 {
-					startY = int(y - 5)
-				} else {
-					startY = 0
-				}
-			}
-		}
+	at := func(x, y int) float64 {
+		return float64(matchResult.GetFloatAt(y, x))
+	}
+	for i := range peaks {
+		peaks[i].subX, peaks[i].subY = ddddgocr.RefinePeak(at, peaks[i].loc.X, peaks[i].loc.Y, matchResult.Cols(), matchResult.Rows(), mode)
+	}
+}
 
-		// 如果该列有足够的差异像素，说明找到了坑位的起始位置
-		if count >= 5 {
-			startX = int(x + 2) // 稍微向右偏移2个像素
-			break
-		}
+// peakBoxes 峰值处模板覆盖的区域，用作轮廓匹配的搜索窗口
+func peakBoxes(peaks []peak, width, height int) []image.Rectangle {
+	boxes := make([]image.Rectangle, len(peaks))
+	for i, p := range peaks {
+		boxes[i] = image.Rectangle{Min: p.loc, Max: p.loc.Add(image.Pt(width, height))}
 	}
+	return boxes
+}
 
-	return &ddddgocr.SlideBBox{
-		X1: startX,
-		Y1: startY,
-	}, nil
+// peakResult 由峰值构造匹配结果，候选数量不超过k
+func peakResult(peaks []peak, width, height, targetY, k int, strategy ddddgocr.Strategy) *ddddgocr.SlideResult {
+	best := peaks[0]
+	second := 0.0
+	if len(peaks) > 1 && peaks[1].score > 0 {
+		second = peaks[1].score
+	}
+
+	candidates := make([]ddddgocr.SlideCandidate, 0, k)
+	for i := 0; i < len(peaks) && i < k; i++ {
+		candidates = append(candidates, ddddgocr.SlideCandidate{
+			SlideBBox: ddddgocr.SlideBBox{
+				TargetY: targetY,
+				X1:      peaks[i].loc.X,
+				Y1:      peaks[i].loc.Y,
+				X2:      peaks[i].loc.X + width,
+				Y2:      peaks[i].loc.Y + height,
+				SubX:    peaks[i].subX,
+				SubY:    peaks[i].subY,
+			},
+			Score:    peaks[i].score,
+			Strategy: strategy,
+		})
+	}
+
+	return &ddddgocr.SlideResult{
+		SlideBBox:  candidates[0].SlideBBox,
+		Score:      best.score,
+		Margin:     best.score - second,
+		Strategy:   strategy,
+		Candidates: candidates,
+	}
 }
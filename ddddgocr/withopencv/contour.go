@@ -0,0 +1,150 @@
+package withopencv
+
+import (
+	"context"
+	"image"
+	"time"
+
+	"github.com/Dainsleif233/ddddGocr/ddddgocr"
+	"gocv.io/x/gocv"
+)
+
+// 滑块轮廓模板匹配时，取作搜索窗口的峰值数，与纯Go引擎一致
+const contourAnchors = 3
+
+// ContourSlideMatch 轮廓形状匹配，用滑块透明通道的轮廓与背景边缘中的封闭轮廓比较形状，适合缺口只有淡描边的情况
+func ContourSlideMatch(targetImageData, backgroundImageData []byte, opts ...ddddgocr.Option) (*ddddgocr.SlideResult, error) {
+	return ContourSlideMatchContext(context.Background(), targetImageData, backgroundImageData, opts...)
+}
+
+// ContourSlideMatchContext 可取消的轮廓形状匹配，仅在各步骤之间检查取消
+func ContourSlideMatchContext(ctx context.Context, targetImageData, backgroundImageData []byte, opts ...ddddgocr.Option) (*ddddgocr.SlideResult, error) {
+	start := time.Now()
+
+	// 从字节数据解码为Mat，保留滑块的透明通道
+	targetMat, backgroundMat, err := decodeMats(targetImageData, backgroundImageData, true)
+	if err != nil {
+		return nil, err
+	}
+	defer targetMat.Close()
+	defer backgroundMat.Close()
+
+	return contourSlideMatch(ctx, start, targetMat, backgroundMat, ddddgocr.NewOptions(opts...))
+}
+
+// ContourSlideMatchImage 对已解码图像进行轮廓形状匹配
+func ContourSlideMatchImage(ctx context.Context, targetImg, backgroundImg image.Image, opts ...ddddgocr.Option) (*ddddgocr.SlideResult, error) {
+	start := time.Now()
+
+	targetMat, backgroundMat, err := imagesToMats(targetImg, backgroundImg, true)
+	if err != nil {
+		return nil, err
+	}
+	defer targetMat.Close()
+	defer backgroundMat.Close()
+
+	return contourSlideMatch(ctx, start, targetMat, backgroundMat, ddddgocr.NewOptions(opts...))
+}
+
+// 轮廓形状匹配流程，start为计时起点；边缘检测由OpenCV完成，轮廓分析与纯Go引擎共用，
+// 滑块没有透明通道时无法取得轮廓，返回ErrNoMatch
+func contourSlideMatch(ctx context.Context, start time.Time, targetMat, backgroundMat gocv.Mat, o *ddddgocr.Options) (*ddddgocr.SlideResult, error) {
+	// 检查图像尺寸
+	if err := ddddgocr.CheckContains(matSize(targetMat), matSize(backgroundMat)); err != nil {
+		return nil, err
+	}
+	if targetMat.Channels() != 4 {
+		return nil, ddddgocr.ErrNoMatch
+	}
+
+	// 裁剪透明区域，透明通道即滑块形状
+	cropped, startY, startX := cropTransparentOpenCV(targetMat)
+	defer cropped.Close()
+	shape, err := alphaShape(cropped)
+	if err != nil {
+		return nil, err
+	}
+
+	// 缺口常只有淡描边，使用低阈值边缘
+	backgroundGray := gocv.NewMat()
+	defer backgroundGray.Close()
+	gocv.CvtColor(backgroundMat, &backgroundGray, gocv.ColorBGRToGray)
+
+	backgroundEdges := gocv.NewMat()
+	defer backgroundEdges.Close()
+	gocv.Canny(backgroundGray, &backgroundEdges, float32(o.CannyLow.Low), float32(o.CannyLow.High))
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	region := o.SearchRegion(image.Rect(0, 0, backgroundEdges.Cols(), backgroundEdges.Rows()), shape.Bounds().Size(), &image.Point{X: startX, Y: startY})
+
+	// 滑块轮廓的模板匹配峰值作为轮廓搜索窗口
+	searchEdges := backgroundEdges.Region(region)
+	defer searchEdges.Close()
+	anchors, err := outlineAnchors(searchEdges, shape, max(o.TopK, contourAnchors))
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := matchContour(ctx, backgroundEdges, region, shape, anchors, startY, o)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, ddddgocr.ErrNoMatch
+	}
+	if result.Score < o.ContourMinScore {
+		return nil, &ddddgocr.LowQualityError{Score: result.Score, Threshold: o.ContourMinScore, Strategy: ddddgocr.StrategyContour}
+	}
+
+	result.Translate(region.Min)
+	return finishResult(result, start), nil
+}
+
+// 在边缘图的region范围内、anchors各候选框附近进行轮廓分析，anchors与结果均为region内的坐标
+func matchContour(ctx context.Context, edgesMat gocv.Mat, region image.Rectangle, shape *image.Alpha, anchors []image.Rectangle, targetY int, o *ddddgocr.Options) (*ddddgocr.SlideResult, error) {
+	searchEdges := edgesMat.Region(region)
+	defer searchEdges.Close()
+	// Region得到的子矩阵不连续，复制后再转换
+	edgesCopy := searchEdges.Clone()
+	defer edgesCopy.Close()
+
+	edges, err := toGray(edgesCopy)
+	if err != nil {
+		return nil, err
+	}
+	return ddddgocr.MatchContour(ctx, edges, shape, anchors, targetY, o)
+}
+
+// 滑块轮廓与边缘图edges模板匹配的前k个峰值处的候选框，轮廓与纯Go引擎相同
+func outlineAnchors(edges gocv.Mat, shape *image.Alpha, k int) ([]image.Rectangle, error) {
+	outline, err := gocv.ImageGrayToMatGray(ddddgocr.ShapeOutline(shape))
+	if err != nil {
+		return nil, err
+	}
+	defer outline.Close()
+
+	noMask := gocv.NewMat()
+	defer noMask.Close()
+	matchResult := gocv.NewMat()
+	defer matchResult.Close()
+	matchTemplate(edges, outline, noMask, &matchResult)
+	return peakBoxes(findPeaks(matchResult, k, outline.Cols(), outline.Rows()), outline.Cols(), outline.Rows()), nil
+}
+
+// 取BGRA图像的透明通道作为滑块形状
+func alphaShape(img gocv.Mat) (*image.Alpha, error) {
+	alpha := gocv.NewMat()
+	defer alpha.Close()
+	gocv.ExtractChannel(img, &alpha, 3)
+
+	gray, err := toGray(alpha)
+	if err != nil {
+		return nil, err
+	}
+	shape := image.NewAlpha(gray.Bounds())
+	copy(shape.Pix, gray.Pix)
+	return shape, nil
+}
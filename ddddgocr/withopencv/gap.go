@@ -0,0 +1,83 @@
+package withopencv
+
+import (
+	"context"
+	"image"
+	"time"
+
+	"github.com/Dainsleif233/ddddGocr/ddddgocr"
+	"gocv.io/x/gocv"
+)
+
+// GapMatch 缺口定位，只根据背景图片寻找加深或描边的缺口，不需要滑块图片
+func GapMatch(backgroundImageData []byte, opts ...ddddgocr.Option) (*ddddgocr.SlideResult, error) {
+	return GapMatchContext(context.Background(), backgroundImageData, opts...)
+}
+
+// GapMatchContext 可取消的缺口定位，仅在各步骤之间检查取消
+func GapMatchContext(ctx context.Context, backgroundImageData []byte, opts ...ddddgocr.Option) (*ddddgocr.SlideResult, error) {
+	start := time.Now()
+
+	backgroundMat, err := decodeMat(backgroundImageData, ddddgocr.RoleBackground, gocv.IMReadColor)
+	if err != nil {
+		return nil, err
+	}
+	defer backgroundMat.Close()
+
+	return gapMatch(ctx, start, backgroundMat, ddddgocr.NewOptions(opts...))
+}
+
+// GapMatchImage 对已解码图像进行缺口定位
+func GapMatchImage(ctx context.Context, backgroundImg image.Image, opts ...ddddgocr.Option) (*ddddgocr.SlideResult, error) {
+	start := time.Now()
+
+	backgroundMat, err := imageToMat(backgroundImg, false)
+	if err != nil {
+		return nil, &ddddgocr.DecodeError{Image: ddddgocr.RoleBackground, Err: err}
+	}
+	defer backgroundMat.Close()
+
+	return gapMatch(ctx, start, backgroundMat, ddddgocr.NewOptions(opts...))
+}
+
+// 缺口定位流程，start为计时起点；滤波与边缘检测由OpenCV完成，区域分析与纯Go引擎共用
+func gapMatch(ctx context.Context, start time.Time, backgroundMat gocv.Mat, o *ddddgocr.Options) (*ddddgocr.SlideResult, error) {
+	// 转换为灰度图
+	grayMat := gocv.NewMat()
+	defer grayMat.Close()
+	gocv.CvtColor(backgroundMat, &grayMat, gocv.ColorBGRToGray)
+
+	// 局部均值，窗口远大于缺口，缺口内的均值接近周围的亮度
+	meanMat := gocv.NewMat()
+	defer meanMat.Close()
+	size := 2*o.GapMaxSize + 1
+	gocv.Blur(grayMat, &meanMat, image.Pt(size, size))
+
+	// 边缘检测
+	edgesMat := gocv.NewMat()
+	defer edgesMat.Close()
+	gocv.Canny(grayMat, &edgesMat, float32(o.Canny.Low), float32(o.Canny.High))
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	gray, err := toGray(grayMat)
+	if err != nil {
+		return nil, err
+	}
+	mean, err := toGray(meanMat)
+	if err != nil {
+		return nil, err
+	}
+	edges, err := toGray(edgesMat)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := ddddgocr.LocateGap(ctx, gray, mean, edges, o)
+	if err != nil {
+		return nil, err
+	}
+	return finishResult(result, start), nil
+}
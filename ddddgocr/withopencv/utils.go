@@ -0,0 +1,285 @@
+package withopencv
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/Dainsleif233/ddddGocr/ddddgocr"
+	"gocv.io/x/gocv"
+)
+
+// decodeMats 解码目标图像与背景图像，keepAlpha时目标图像保留透明通道
+func decodeMats(targetImageData, backgroundImageData []byte, keepAlpha bool) (gocv.Mat, gocv.Mat, error) {
+	targetFlag := gocv.IMReadColor
+	if keepAlpha {
+		targetFlag = gocv.IMReadUnchanged
+	}
+
+	targetMat, err := decodeMat(targetImageData, ddddgocr.RoleTarget, targetFlag)
+	if err != nil {
+		return gocv.Mat{}, gocv.Mat{}, err
+	}
+
+	backgroundMat, err := decodeMat(backgroundImageData, ddddgocr.RoleBackground, gocv.IMReadColor)
+	if err != nil {
+		targetMat.Close()
+		return gocv.Mat{}, gocv.Mat{}, err
+	}
+
+	return targetMat, backgroundMat, nil
+}
+
+// decodeMat 解码单张图像，无法识别的数据会得到空Mat；
+// IMReadUnchanged解码的结果统一为8位BGR，带透明通道时为8位BGRA
+func decodeMat(data []byte, role ddddgocr.ImageRole, flag gocv.IMReadFlag) (gocv.Mat, error) {
+	mat, err := gocv.IMDecode(data, flag)
+	if err != nil {
+		return gocv.Mat{}, &ddddgocr.DecodeError{Image: role, Err: err}
+	}
+	if mat.Empty() {
+		mat.Close()
+		return gocv.Mat{}, &ddddgocr.DecodeError{Image: role, Err: ddddgocr.ErrEmptyImage}
+	}
+	if flag != gocv.IMReadUnchanged {
+		return mat, nil
+	}
+
+	normalized, err := normalizeMat(mat)
+	mat.Close()
+	if err != nil {
+		return gocv.Mat{}, &ddddgocr.DecodeError{Image: role, Err: err}
+	}
+	return normalized, nil
+}
+
+// normalizeMat 将原样解码的Mat转换为8位，单通道灰度图转换为BGR
+func normalizeMat(mat gocv.Mat) (gocv.Mat, error) {
+	result := mat.Clone()
+
+	// 16位图像缩放到8位，浮点图像按[0, 1]范围缩放
+	if depth := mat.Type() & 7; depth != gocv.MatTypeCV8U {
+		scale := float32(1)
+		switch depth {
+		case gocv.MatTypeCV16U:
+			scale = 1.0 / 257
+		case gocv.MatTypeCV32F, gocv.MatTypeCV64F:
+			scale = 255
+		}
+		converted := gocv.NewMat()
+		err := result.ConvertToWithParams(&converted, gocv.MatTypeCV8U+(mat.Type()&^7), scale, 0)
+		result.Close()
+		if err != nil {
+			converted.Close()
+			return gocv.Mat{}, err
+		}
+		result = converted
+	}
+
+	if result.Channels() == 1 {
+		bgr := gocv.NewMat()
+		gocv.CvtColor(result, &bgr, gocv.ColorGrayToBGR)
+		result.Close()
+		result = bgr
+	}
+
+	return result, nil
+}
+
+// imagesToMats 将目标图像与背景图像转换为Mat，keepAlpha时目标图像保留透明通道
+func imagesToMats(targetImg, backgroundImg image.Image, keepAlpha bool) (gocv.Mat, gocv.Mat, error) {
+	targetMat, err := imageToMat(targetImg, keepAlpha)
+	if err != nil {
+		return gocv.Mat{}, gocv.Mat{}, &ddddgocr.DecodeError{Image: ddddgocr.RoleTarget, Err: err}
+	}
+
+	backgroundMat, err := imageToMat(backgroundImg, false)
+	if err != nil {
+		targetMat.Close()
+		return gocv.Mat{}, gocv.Mat{}, &ddddgocr.DecodeError{Image: ddddgocr.RoleBackground, Err: err}
+	}
+
+	return targetMat, backgroundMat, nil
+}
+
+// imageToMat 将image.Image转换为BGR三通道Mat，与IMReadColor解码结果一致；
+// keepAlpha且图像存在透明像素时转换为BGRA四通道Mat，与IMReadUnchanged解码结果一致
+func imageToMat(img image.Image, keepAlpha bool) (gocv.Mat, error) {
+	bounds := img.Bounds()
+	data := make([]byte, 0, bounds.Dx()*bounds.Dy()*4)
+	opaque := true
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			data = append(data, c.B, c.G, c.R, c.A)
+			opaque = opaque && c.A == 255
+		}
+	}
+
+	if keepAlpha && !opaque {
+		return gocv.NewMatFromBytes(bounds.Dy(), bounds.Dx(), gocv.MatTypeCV8UC4, data)
+	}
+
+	// 去掉透明通道
+	bgr := data[:0]
+	for i := 0; i < len(data); i += 4 {
+		bgr = append(bgr, data[i], data[i+1], data[i+2])
+	}
+	return gocv.NewMatFromBytes(bounds.Dy(), bounds.Dx(), gocv.MatTypeCV8UC3, bgr)
+}
+
+// splitAlpha 将BGRA图像的透明像素置为黑色并转换为BGR，与纯Go引擎一致；
+// useMask时同时返回透明通道的二值掩码，图像完全不透明或完全透明时掩码为空Mat
+func splitAlpha(img gocv.Mat, useMask bool) (gocv.Mat, gocv.Mat) {
+	alpha := gocv.NewMat()
+	defer alpha.Close()
+	gocv.ExtractChannel(img, &alpha, 3)
+
+	binary := gocv.NewMat()
+	gocv.Threshold(alpha, &binary, 0, 255, gocv.ThresholdBinary)
+
+	bgr := gocv.NewMat()
+	defer bgr.Close()
+	gocv.CvtColor(img, &bgr, gocv.ColorBGRAToBGR)
+
+	flattened := gocv.Zeros(bgr.Rows(), bgr.Cols(), bgr.Type())
+	bgr.CopyToWithMask(&flattened, binary)
+
+	opaque := gocv.CountNonZero(binary)
+	if !useMask || opaque == 0 || opaque == binary.Rows()*binary.Cols() {
+		binary.Close()
+		return flattened, gocv.NewMat()
+	}
+	return flattened, binary
+}
+
+// matchTemplate 标准化相关系数模板匹配，mask非空时只统计掩码内的像素；
+// 带掩码时方差为零的位置会得到NaN或Inf，统一置为0
+func matchTemplate(background, template, mask gocv.Mat, result *gocv.Mat) {
+	gocv.MatchTemplate(background, template, result, gocv.TmCcoeffNormed, mask)
+	if mask.Empty() {
+		return
+	}
+
+	scores, err := result.DataPtrFloat32()
+	if err != nil {
+		return
+	}
+	for i, v := range scores {
+		if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+			scores[i] = 0
+		}
+	}
+}
+
+// scalePiece 按倍数缩放滑块灰度图与掩码，灰度图双线性插值，掩码取最近邻；
+// 返回的Mat均需由调用方关闭，掩码为空时仍返回空Mat
+func scalePiece(gray, mask gocv.Mat, scale float64) (gocv.Mat, gocv.Mat) {
+	if scale == 1 {
+		return gray.Clone(), mask.Clone()
+	}
+
+	size := image.Pt(max(1, int(math.Round(float64(gray.Cols())*scale))), max(1, int(math.Round(float64(gray.Rows())*scale))))
+	scaledGray := gocv.NewMat()
+	gocv.Resize(gray, &scaledGray, size, 0, 0, gocv.InterpolationLinear)
+
+	scaledMask := gocv.NewMat()
+	if !mask.Empty() {
+		gocv.Resize(mask, &scaledMask, size, 0, 0, gocv.InterpolationNearestNeighbor)
+	}
+	return scaledGray, scaledMask
+}
+
+// rotatePiece 按角度（度，顺时针）绕中心旋转滑块灰度图与掩码，画布扩大到能容纳旋转结果后裁剪到不透明区域，
+// 与纯Go引擎一致：灰度图双线性插值，掩码取最近邻，mask应为滑块形状，为空时视为完全不透明，掩码以外的像素为0，
+// 是否以返回的掩码参与相关计算由调用方决定；offset为裁剪结果左上角相对旋转前左上角的偏移，角度为0时返回副本；
+// 出错时返回两个空Mat，调用方检查错误后同样需要关闭
+func rotatePiece(gray, mask gocv.Mat, degrees float64) (gocv.Mat, gocv.Mat, image.Point, error) {
+	if degrees == 0 {
+		return gray.Clone(), mask.Clone(), image.Point{}, nil
+	}
+
+	srcW, srcH := float64(gray.Cols()), float64(gray.Rows())
+	sin, cos := math.Sincos(degrees * math.Pi / 180)
+	width := int(math.Ceil(math.Abs(srcW*cos) + math.Abs(srcH*sin) - 1e-9))
+	height := int(math.Ceil(math.Abs(srcW*sin) + math.Abs(srcH*cos) - 1e-9))
+
+	// 绕中心顺时针旋转并平移到新画布中心，OpenCV像素中心位于整数坐标
+	m := gocv.NewMatWithSize(2, 3, gocv.MatTypeCV64F)
+	defer m.Close()
+	cx, cy := (srcW-1)/2, (srcH-1)/2
+	m.SetDoubleAt(0, 0, cos)
+	m.SetDoubleAt(0, 1, -sin)
+	m.SetDoubleAt(0, 2, (float64(width)-1)/2-(cos*cx-sin*cy))
+	m.SetDoubleAt(1, 0, sin)
+	m.SetDoubleAt(1, 1, cos)
+	m.SetDoubleAt(1, 2, (float64(height)-1)/2-(sin*cx+cos*cy))
+
+	source := mask
+	if mask.Empty() {
+		source = gocv.NewMatWithSizeFromScalar(gocv.NewScalar(255, 0, 0, 0), gray.Rows(), gray.Cols(), gocv.MatTypeCV8U)
+		defer source.Close()
+	}
+	size := image.Pt(width, height)
+	rotatedMask := gocv.NewMat()
+	defer rotatedMask.Close()
+	if err := gocv.WarpAffineWithParams(source, &rotatedMask, m, size, gocv.InterpolationNearestNeighbor, gocv.BorderConstant, color.RGBA{}); err != nil {
+		return gocv.NewMat(), gocv.NewMat(), image.Point{}, err
+	}
+	warped := gocv.NewMat()
+	defer warped.Close()
+	if err := gocv.WarpAffineWithParams(gray, &warped, m, size, gocv.InterpolationLinear, gocv.BorderConstant, color.RGBA{}); err != nil {
+		return gocv.NewMat(), gocv.NewMat(), image.Point{}, err
+	}
+
+	// 掩码以外置0
+	rotated := gocv.Zeros(height, width, gray.Type())
+	defer rotated.Close()
+	if err := warped.CopyToWithMask(&rotated, rotatedMask); err != nil {
+		return gocv.NewMat(), gocv.NewMat(), image.Point{}, err
+	}
+
+	// 不透明区域
+	maskGray, err := toGray(rotatedMask)
+	if err != nil {
+		return gocv.NewMat(), gocv.NewMat(), image.Point{}, err
+	}
+	opaque := image.Rectangle{}
+	for y := range height {
+		for x := range width {
+			if maskGray.Pix[y*maskGray.Stride+x] != 0 {
+				opaque = opaque.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	if opaque.Empty() {
+		return rotated.Clone(), rotatedMask.Clone(), image.Point{}, nil
+	}
+
+	croppedGray := rotated.Region(opaque)
+	defer croppedGray.Close()
+	croppedMask := rotatedMask.Region(opaque)
+	defer croppedMask.Close()
+
+	// 旋转画布与原图中心重合
+	offset := opaque.Min.Add(image.Pt(
+		int(math.Round((srcW-float64(width))/2)),
+		int(math.Round((srcH-float64(height))/2)),
+	))
+	return croppedGray.Clone(), croppedMask.Clone(), offset, nil
+}
+
+// matSize Mat的宽高
+func matSize(mat gocv.Mat) image.Point {
+	return image.Pt(mat.Cols(), mat.Rows())
+}
+
+// toGray 将单通道8位Mat转换为灰度图
+func toGray(mat gocv.Mat) (*image.Gray, error) {
+	img, err := mat.ToImage()
+	if err != nil {
+		return nil, err
+	}
+	return img.(*image.Gray), nil
+}
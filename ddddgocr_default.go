@@ -1,14 +1,56 @@
-//go:build !opencv
-// +build !opencv
-
 package ddddGocr
 
 import (
-	"fmt"
+	"context"
 
 	"github.com/Dainsleif233/ddddGocr/ddddgocr"
 )
 
-func slideMatchWithOpenCV(_, _ []byte, _ SlideMatchType) (*ddddgocr.SlideBBox, error) {
-	return nil, fmt.Errorf("OpenCV 支持未启用，请使用 -tags opencv 编译")
+func init() {
+	RegisterEngine(Default, defaultEngine{})
+}
+
+// 纯Go实现的默认引擎
+type defaultEngine struct{}
+
+func (defaultEngine) Match(ctx context.Context, req *MatchRequest) (*ddddgocr.SlideResult, error) {
+	// 缺口定位只需要背景图片
+	if req.Type == Gap {
+		if req.BackgroundImage != nil {
+			return ddddgocr.GapMatchImage(ctx, req.BackgroundImage, req.Options...)
+		}
+		return ddddgocr.GapMatchContext(ctx, req.Background, req.Options...)
+	}
+
+	if req.hasImages() {
+		switch req.Type {
+		case Simple:
+			return ddddgocr.SimpleSlideMatchImage(ctx, req.TargetImage, req.BackgroundImage, req.Options...)
+		case Standard:
+			return ddddgocr.SlideMatchImage(ctx, req.TargetImage, req.BackgroundImage, req.Options...)
+		case Enhanced:
+			return ddddgocr.EnhancedSlideMatchImage(ctx, req.TargetImage, req.BackgroundImage, req.Options...)
+		case Comparison:
+			return ddddgocr.SlideComparisonImage(ctx, req.TargetImage, req.BackgroundImage, req.Options...)
+		case Contour:
+			return ddddgocr.ContourSlideMatchImage(ctx, req.TargetImage, req.BackgroundImage, req.Options...)
+		default:
+			return nil, &ddddgocr.UnknownMatchTypeError{Type: string(req.Type)}
+		}
+	}
+
+	switch req.Type {
+	case Simple:
+		return ddddgocr.SimpleSlideMatchContext(ctx, req.Target, req.Background, req.Options...)
+	case Standard:
+		return ddddgocr.SlideMatchContext(ctx, req.Target, req.Background, req.Options...)
+	case Enhanced:
+		return ddddgocr.EnhancedSlideMatchContext(ctx, req.Target, req.Background, req.Options...)
+	case Comparison:
+		return ddddgocr.SlideComparisonContext(ctx, req.Target, req.Background, req.Options...)
+	case Contour:
+		return ddddgocr.ContourSlideMatchContext(ctx, req.Target, req.Background, req.Options...)
+	default:
+		return nil, &ddddgocr.UnknownMatchTypeError{Type: string(req.Type)}
+	}
 }
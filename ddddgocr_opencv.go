@@ -4,23 +4,57 @@
 package ddddGocr
 
 import (
-	"fmt"
+	"context"
 
 	"github.com/Dainsleif233/ddddGocr/ddddgocr"
 	"github.com/Dainsleif233/ddddGocr/ddddgocr/withopencv"
 )
 
-func slideMatchWithOpenCV(targetData, backgroundData []byte, matchType SlideMatchType) (*ddddgocr.SlideBBox, error) {
-	switch matchType {
+func init() {
+	RegisterEngine(OpenCV, opencvEngine{})
+}
+
+// 基于OpenCV的引擎
+type opencvEngine struct{}
+
+func (opencvEngine) Match(ctx context.Context, req *MatchRequest) (*ddddgocr.SlideResult, error) {
+	// 缺口定位只需要背景图片
+	if req.Type == Gap {
+		if req.BackgroundImage != nil {
+			return withopencv.GapMatchImage(ctx, req.BackgroundImage, req.Options...)
+		}
+		return withopencv.GapMatchContext(ctx, req.Background, req.Options...)
+	}
+
+	if req.hasImages() {
+		switch req.Type {
+		case Simple:
+			return withopencv.SimpleSlideMatchImage(ctx, req.TargetImage, req.BackgroundImage, req.Options...)
+		case Standard:
+			return withopencv.SlideMatchImage(ctx, req.TargetImage, req.BackgroundImage, req.Options...)
+		case Enhanced:
+			return withopencv.EnhancedSlideMatchImage(ctx, req.TargetImage, req.BackgroundImage, req.Options...)
+		case Comparison:
+			return withopencv.SlideComparisonImage(ctx, req.TargetImage, req.BackgroundImage, req.Options...)
+		case Contour:
+			return withopencv.ContourSlideMatchImage(ctx, req.TargetImage, req.BackgroundImage, req.Options...)
+		default:
+			return nil, &ddddgocr.UnknownMatchTypeError{Type: string(req.Type)}
+		}
+	}
+
+	switch req.Type {
 	case Simple:
-		return withopencv.SimpleSlideMatch(targetData, backgroundData)
+		return withopencv.SimpleSlideMatchContext(ctx, req.Target, req.Background, req.Options...)
 	case Standard:
-		return withopencv.SlideMatch(targetData, backgroundData)
+		return withopencv.SlideMatchContext(ctx, req.Target, req.Background, req.Options...)
 	case Enhanced:
-		return withopencv.EnhancedSlideMatch(targetData, backgroundData)
+		return withopencv.EnhancedSlideMatchContext(ctx, req.Target, req.Background, req.Options...)
 	case Comparison:
-		return withopencv.SlideComparison(targetData, backgroundData)
+		return withopencv.SlideComparisonContext(ctx, req.Target, req.Background, req.Options...)
+	case Contour:
+		return withopencv.ContourSlideMatchContext(ctx, req.Target, req.Background, req.Options...)
 	default:
-		return nil, fmt.Errorf("匹配类型错误")
+		return nil, &ddddgocr.UnknownMatchTypeError{Type: string(req.Type)}
 	}
 }
@@ -0,0 +1,88 @@
+package ddddGocr
+
+import (
+	"context"
+	"image"
+	"sort"
+	"sync"
+
+	"github.com/Dainsleif233/ddddGocr/ddddgocr"
+)
+
+// MatchRequest 一次匹配请求，
+// TargetImage与BackgroundImage均不为空时优先使用已解码图像
+type MatchRequest struct {
+	Type       SlideMatchType    // 匹配方式
+	Target     []byte            // 目标图片，比较模式下为完整图片
+	Background []byte            // 背景图片
+	Options    []ddddgocr.Option // 匹配参数
+
+	TargetImage     image.Image // 已解码的目标图片
+	BackgroundImage image.Image // 已解码的背景图片
+}
+
+// 是否携带已解码图像
+func (req *MatchRequest) hasImages() bool {
+	return req.TargetImage != nil && req.BackgroundImage != nil
+}
+
+// Engine 匹配引擎，实现后通过RegisterEngine注册即可按名称调用
+type Engine interface {
+	Match(ctx context.Context, req *MatchRequest) (*ddddgocr.SlideResult, error)
+}
+
+// EngineFunc 以函数实现Engine
+type EngineFunc func(ctx context.Context, req *MatchRequest) (*ddddgocr.SlideResult, error)
+
+// Match 调用f本身
+func (f EngineFunc) Match(ctx context.Context, req *MatchRequest) (*ddddgocr.SlideResult, error) {
+	return f(ctx, req)
+}
+
+var (
+	enginesMu sync.RWMutex
+	engines   = make(map[MatchEngine]Engine)
+)
+
+// RegisterEngine 注册匹配引擎，同名引擎会被替换，engine为nil时注销该名称
+func RegisterEngine(name MatchEngine, engine Engine) {
+	enginesMu.Lock()
+	defer enginesMu.Unlock()
+
+	if engine == nil {
+		delete(engines, name)
+		return
+	}
+	engines[name] = engine
+}
+
+// Engines 已注册的匹配引擎名称
+func Engines() []MatchEngine {
+	enginesMu.RLock()
+	defer enginesMu.RUnlock()
+
+	names := make([]MatchEngine, 0, len(engines))
+	for name := range engines {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// 按名称查找引擎，名称为空时使用默认引擎
+func lookupEngine(name MatchEngine) (Engine, error) {
+	if name == "" {
+		name = Default
+	}
+
+	enginesMu.RLock()
+	engine, ok := engines[name]
+	enginesMu.RUnlock()
+
+	if !ok {
+		return nil, &ddddgocr.EngineUnavailableError{Engine: string(name)}
+	}
+	return engine, nil
+}